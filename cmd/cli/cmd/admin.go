@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"ogugu/internal/database"
+	"ogugu/internal/repository/users"
+)
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Grant or revoke the admin role of a user",
+	Long: `Grant the user with --email the admin role, or revoke it with --revoke.
+Admins can change settings of the feeds every subscriber shares.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := cmd.Flags().GetString("database")
+		dbConn, err := database.New("pgx", db)
+		if err != nil {
+			fmt.Println("unable to initialize database", err.Error())
+			os.Exit(1)
+		}
+		email, _ := cmd.Flags().GetString("email")
+		revoke, _ := cmd.Flags().GetBool("revoke")
+
+		n, err := users.New(dbConn).SetAdmin(context.Background(), email, !revoke)
+		if err != nil {
+			fmt.Println("could not update user", err.Error())
+			os.Exit(1)
+		}
+		if n == 0 {
+			fmt.Println("no user with email " + email)
+			os.Exit(1)
+		}
+		fmt.Println("success!")
+	},
+}
+
+func init() {
+	adminCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	adminCmd.Flags().StringP("email", "e", "", "email address of the user")
+	adminCmd.Flags().Bool("revoke", false, "revoke the admin role instead of granting it")
+	for _, flag := range []string{"database", "email"} {
+		if err := adminCmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+	rootCmd.AddCommand(adminCmd)
+}
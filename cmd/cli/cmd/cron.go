@@ -6,9 +6,8 @@ package cmd
 import (
 	"context"
 	"database/sql"
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"time"
@@ -17,10 +16,20 @@ import (
 
 	"github.com/oklog/ulid/v2"
 
+	"ogugu/internal/config"
 	"ogugu/internal/database"
+	"ogugu/internal/extract"
+	"ogugu/internal/favicon"
+	"ogugu/internal/fetcher"
+	"ogugu/internal/filters"
 	"ogugu/internal/models"
+	"ogugu/internal/orphans"
+	"ogugu/internal/repository/filterrules"
 	"ogugu/internal/repository/posts"
 	"ogugu/internal/repository/rss"
+	"ogugu/internal/safehttp"
+	"ogugu/internal/simhash"
+	"ogugu/internal/urlnorm"
 )
 
 // cronCmd represents the cron command
@@ -35,14 +44,30 @@ var cronCmd = &cobra.Command{
 			fmt.Println("unable to initialize database", err.Error())
 			os.Exit(1)
 		}
-		if err := job(dbConn); err == nil {
-			fmt.Println("success!")
+		client := safehttp.NewClient(safehttp.ConfigFromEnv())
+		f := fetcher.New(client)
+		x := extract.New(client, extract.ConfigFromEnv())
+		if err := job(dbConn, client, f, x); err != nil {
+			return
 		}
+		if ok, _ := cmd.Flags().GetBool("gc"); ok {
+			if err := collectOrphans(dbConn, time.Now(), orphans.PolicyFromEnv()); err != nil {
+				return
+			}
+		}
+		if ok, _ := cmd.Flags().GetBool("prune"); ok {
+			if err := prune(dbConn, time.Now(), false); err != nil {
+				return
+			}
+		}
+		fmt.Println("success!")
 	},
 }
 
 func init() {
 	cronCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	cronCmd.Flags().Bool("gc", false, "archive and delete feeds without subscribers after fetching")
+	cronCmd.Flags().Bool("prune", false, "delete posts past their retention after fetching")
 	if err := cronCmd.MarkFlagRequired("database"); err != nil {
 		panic(err)
 	}
@@ -51,8 +76,24 @@ func init() {
 	// rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func job(db *sql.DB) error {
+func job(db *sql.DB, client *http.Client, f fetcher.Fetcher, x *extract.Extractor) error {
 	rssSrv := rss.New(db)
+	iconRefresh := config.Duration("FEED_ICON_REFRESH", 7*24*time.Hour)
+	policy := orphans.PolicyFromEnv()
+
+	// feeds and posts added before feed keys and clustering existed are
+	// filled in on the first run
+	if _, _, err := rssSrv.BackfillFeedKeys(context.Background()); err != nil {
+		fmt.Println("could not backfill feed keys", err.Error())
+	}
+	if _, err := posts.New(db).BackfillCanonicalURLs(context.Background()); err != nil {
+		fmt.Println("could not backfill post canonical urls", err.Error())
+	}
+
+	if _, _, err := rssSrv.MarkOrphans(context.Background(), time.Now()); err != nil {
+		fmt.Println("could not mark orphaned rss feeds", err.Error())
+		return err
+	}
 
 	feeds, err := rssSrv.Fetch(context.Background())
 	if err != nil {
@@ -61,66 +102,200 @@ func job(db *sql.DB) error {
 	}
 
 	for _, feed := range feeds {
-		res, err := http.Get(feed.RSSLink)
+		if !policy.ShouldFetch(feed, time.Now()) {
+			continue
+		}
+		// feeds that were never fetched are downloaded in full
+		var validators fetcher.Validators
+		if feed.Fetched {
+			validators.ETag = feed.ETag
+			if feed.LastModified != nil {
+				validators.LastModified = feed.LastModified.UTC().Format(http.TimeFormat)
+			}
+		}
+
+		res, err := f.Fetch(context.Background(), feed.RSSLink, validators)
 		if err != nil {
 			fmt.Println("an error occured while fetching rss data", err.Error())
 			continue
 		}
+		if err := rssSrv.MarkFetched(context.Background(), feed.ID, time.Now()); err != nil {
+			fmt.Println("could not mark rss fetched", err.Error())
+		}
+		if res.NotModified {
+			continue
+		}
 
-		if !feed.Fetched {
-			if err = populate(res, db, feed); err != nil {
-				continue
-			}
-			_, err := rssSrv.UpdateField(context.Background(), feed.ID, "fetched", true)
-			if err != nil {
-				fmt.Println("could not update fetched field ", err.Error())
+		populate(db, x, feed, res.Feed)
+
+		var meta models.RSSMeta
+		meta.Channel.Title = res.Feed.Title
+		meta.Channel.Link = res.Feed.Link
+		meta.Channel.Description = res.Feed.Description
+		meta.Channel.Image = res.Feed.Image
+		meta.Channel.Language = res.Feed.Language
+		meta.Channel.Generator = res.Feed.Generator
+		meta.Channel.Copyright = res.Feed.Copyright
+		meta.Channel.Podcast = (*models.Podcast)(res.Feed.Podcast)
+		if err := rssSrv.UpdateMetadata(context.Background(), feed.ID, meta); err != nil {
+			fmt.Println("could not update rss metadata", err.Error())
+		}
+
+		if feed.IconCheckedAt == nil || time.Since(*feed.IconCheckedAt) > iconRefresh {
+			refreshIcon(rssSrv, client, feed, res.Feed)
+		}
+
+		// without a usable Last-Modified only the etag is sent next time
+		var lastModified *time.Time
+		if t, err := http.ParseTime(res.Validators.LastModified); err == nil {
+			lastModified = &t
+		}
+		if err := rssSrv.UpdateValidators(context.Background(), feed.ID, res.Validators.ETag, lastModified); err != nil {
+			fmt.Println("could not update rss validators", err.Error())
+		}
+	}
+	return nil
+}
+
+func refreshIcon(rssSrv *rss.Repository, client *http.Client, feed models.RssFeed, data fetcher.Feed) {
+	site := data.Link
+	if site == "" {
+		site = feed.Link
+	}
+
+	icon, err := favicon.Discover(context.Background(), client, site, data.Icon)
+	if err != nil {
+		fmt.Println("could not find an icon for "+site, err.Error())
+		if err := rssSrv.MarkIconChecked(context.Background(), feed.ID); err != nil {
+			fmt.Println("could not update rss icon", err.Error())
+		}
+		return
+	}
+
+	err = rssSrv.SetIcon(context.Background(), feed.ID, models.FeedIcon{
+		URL:         icon.URL,
+		ContentType: icon.ContentType,
+		Data:        icon.Data,
+	})
+	if err != nil {
+		fmt.Println("could not update rss icon", err.Error())
+	}
+}
+
+func populate(db *sql.DB, x *extract.Extractor, feed models.RssFeed, data fetcher.Feed) {
+	postSrv := posts.New(db)
+	rules := subscriberRules(db, feed.ID)
+
+	for _, item := range data.Items {
+		if item.Link == "" {
+			continue
+		}
+
+		published := item.Published
+		if published.IsZero() {
+			published = time.Now()
+		}
+
+		post := models.CreatePost{
+			Title:       item.Title,
+			Description: item.Description,
+			Content:     item.Content,
+			Summary:     item.Summary,
+			Link:        item.Link,
+			Thumbnail:   item.Thumbnail,
+			Authors:     item.Authors,
+			Categories:  item.Categories,
+			Episode:     (*models.Episode)(item.Episode),
+			PubDate:     published.Format(time.RFC3339),
+
+			CanonicalURL: urlnorm.Canonical(item.Link),
+		}
+		if h, ok := simhash.Hash(item.Title + " " + item.Summary); ok {
+			post.SimHash = &h
+		}
+		for _, e := range item.Enclosures {
+			post.Enclosures = append(post.Enclosures, models.Enclosure{
+				URL:      e.URL,
+				MimeType: e.Type,
+				Length:   e.Length,
+				Duration: e.Duration,
+			})
+		}
+		created, err := postSrv.CreatePost(context.Background(), ulid.Make().String(), feed.ID, post)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				fmt.Println("could not create a new post", err.Error())
 			}
 			continue
 		}
 
-		lm := res.Header.Get("Last-Modified")
-		if lm == "" {
+		applyRules(postSrv, rules, created)
+
+		// only new posts are extracted, so each page is downloaded once
+		if !feed.FetchFullText {
 			continue
 		}
-		lastModified, err := time.Parse(time.RFC1123, lm)
+		page, err := x.Extract(context.Background(), item.Link)
 		if err != nil {
-			fmt.Println("could not parse last modified time", err.Error())
+			fmt.Println("could not extract full text from "+item.Link, err.Error())
 			continue
 		}
-		if lastModified.After(feed.LastModified) {
-			if err = populate(res, db, feed); err != nil {
-				continue
+		if err := postSrv.SetFullContent(context.Background(), created.ID, page.Content); err != nil {
+			fmt.Println("could not store full text", err.Error())
+		}
+		if canonical := urlnorm.Canonical(page.Canonical); canonical != post.CanonicalURL {
+			if err := postSrv.SetCanonical(context.Background(), created.ID, canonical); err != nil {
+				fmt.Println("could not store canonical url", err.Error())
 			}
-			rssSrv.UpdateField(context.Background(), feed.ID, "last_modified", lastModified)
 		}
 	}
-	return nil
 }
 
-func populate(res *http.Response, db *sql.DB, feed models.RssFeed) error {
-	postSrv := posts.New(db)
-
-	body, err := io.ReadAll(res.Body)
-	defer res.Body.Close()
+// subscriberRules compiles the filter rules of every subscriber of a feed,
+// keyed by user id.
+func subscriberRules(db *sql.DB, rss_id string) map[string]*filters.Set {
+	rules, err := filterrules.New(db).ListBySubscribers(context.Background(), rss_id)
 	if err != nil {
-		fmt.Println("could not read response body ", err.Error())
-		return err
+		fmt.Println("could not get filter rules", err.Error())
+		return nil
 	}
 
-	var data models.RSSItems
-	err = xml.Unmarshal(body, &data)
-	if err != nil {
-		fmt.Println("could not unmarshal data from "+feed.Link, err.Error())
-		return err
+	byUser := map[string][]models.FilterRule{}
+	for _, rule := range rules {
+		byUser[rule.UserID] = append(byUser[rule.UserID], rule)
 	}
 
-	for _, value := range data.Channel.Items {
-		_, err := postSrv.CreatePost(context.Background(), ulid.Make().String(), feed.ID, value)
+	sets := map[string]*filters.Set{}
+	for user, rules := range byUser {
+		set, err := filters.Compile(rules)
 		if err != nil {
-			fmt.Println("could not create a new post", err.Error())
+			fmt.Println("could not compile filter rules of "+user, err.Error())
 			continue
 		}
+		sets[user] = set
 	}
+	return sets
+}
 
-	return nil
+// applyRules marks a new post read or starred for the subscribers whose
+// rules ask for it. Hide rules are applied when timelines are read.
+func applyRules(postSrv *posts.Repository, rules map[string]*filters.Set, post models.Post) {
+	yes := true
+	for user, set := range rules {
+		actions := set.Match(post)
+		if !actions.Read && !actions.Star {
+			continue
+		}
+
+		var read, star *bool
+		if actions.Read {
+			read = &yes
+		}
+		if actions.Star {
+			star = &yes
+		}
+		if _, err := postSrv.SetState(context.Background(), user, post.ID, read, star); err != nil {
+			fmt.Println("could not apply filter rules", err.Error())
+		}
+	}
 }
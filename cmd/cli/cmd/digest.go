@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"ogugu/internal/config"
+	"ogugu/internal/database"
+	"ogugu/internal/digest"
+	"ogugu/internal/mailer"
+	"ogugu/internal/repository/digests"
+	"ogugu/internal/repository/filterrules"
+	"ogugu/internal/repository/subscriptions"
+	"ogugu/internal/repository/users"
+)
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Email the digests that are due",
+	Long: `Email every opted-in user whose daily or weekly digest is due a summary of
+their unread posts. Run it at least hourly so digests go out at the hour
+users chose.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := cmd.Flags().GetString("database")
+		dbConn, err := database.New("pgx", db)
+		if err != nil {
+			fmt.Println("unable to initialize database", err.Error())
+			os.Exit(1)
+		}
+		logger, err := zap.NewProduction()
+		if err != nil {
+			fmt.Println("unable to initialize logger", err.Error())
+			os.Exit(1)
+		}
+		mail, err := mailer.New(logger)
+		if err != nil {
+			fmt.Println("unable to initialize mailer", err.Error())
+			os.Exit(1)
+		}
+		if err := sendDigests(dbConn, mail, time.Now()); err == nil {
+			fmt.Println("success!")
+		}
+	},
+}
+
+func init() {
+	digestCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	if err := digestCmd.MarkFlagRequired("database"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(digestCmd)
+}
+
+func sendDigests(db *sql.DB, mail mailer.Mailer, now time.Time) error {
+	digestSrv := digests.New(db)
+	userSrv := users.New(db)
+	builder := digest.NewBuilder(
+		subscriptions.New(db), filterrules.New(db), config.String("API_URL", "http://localhost:8080"),
+	)
+
+	settings, err := digestSrv.ListEnabled(context.Background())
+	if err != nil {
+		fmt.Println("could not get digest settings from db", err.Error())
+		return err
+	}
+
+	for _, s := range settings {
+		due, err := digest.Due(s, now)
+		if err != nil {
+			fmt.Println("could not schedule digest of "+s.UserID, err.Error())
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		user, err := userSrv.GetUserByID(context.Background(), s.UserID)
+		if err != nil {
+			fmt.Println("could not get user "+s.UserID, err.Error())
+			continue
+		}
+		// digests are only sent to addresses the user proved they own
+		if user.EmailVerifiedAt == nil {
+			continue
+		}
+
+		msg, n, err := builder.Build(context.Background(), user, s, now)
+		if err != nil {
+			fmt.Println("could not build digest of "+s.UserID, err.Error())
+			continue
+		}
+		if n > 0 {
+			if err := mail.Send(context.Background(), msg); err != nil {
+				fmt.Println("could not send digest to "+s.UserID, err.Error())
+				continue
+			}
+		}
+
+		if err := digestSrv.MarkSent(context.Background(), s.UserID, now); err != nil {
+			fmt.Println("could not mark digest sent", err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"ogugu/internal/database"
+	"ogugu/internal/repository/rss"
+)
+
+var feedKeysCmd = &cobra.Command{
+	Use:   "feedkeys",
+	Short: "Fill in the feed keys of existing feeds",
+	Long: `Normalize the feed url of every feed without a feed key so that adding the
+same feed again under a different url finds it. cron does this on every
+run; when several feeds share a key only the oldest gets it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := cmd.Flags().GetString("database")
+		dbConn, err := database.New("pgx", db)
+		if err != nil {
+			fmt.Println("unable to initialize database", err.Error())
+			os.Exit(1)
+		}
+
+		updated, duplicates, err := rss.New(dbConn).BackfillFeedKeys(context.Background())
+		if err != nil {
+			fmt.Println("could not backfill feed keys", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("keyed %d feeds, skipped %d duplicates\n", updated, duplicates)
+		fmt.Println("success!")
+	},
+}
+
+func init() {
+	feedKeysCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	if err := feedKeysCmd.MarkFlagRequired("database"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(feedKeysCmd)
+}
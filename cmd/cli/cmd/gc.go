@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ogugu/internal/database"
+	"ogugu/internal/orphans"
+	"ogugu/internal/repository/rss"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Archive and delete feeds without subscribers",
+	Long: `Archive the feeds that have had no subscribers for ORPHAN_ARCHIVE_AFTER, so
+they are no longer fetched, and delete those without subscribers for
+ORPHAN_DELETE_AFTER. Feeds with starred posts are kept, with only those
+posts, until the posts are unstarred.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := cmd.Flags().GetString("database")
+		dbConn, err := database.New("pgx", db)
+		if err != nil {
+			fmt.Println("unable to initialize database", err.Error())
+			os.Exit(1)
+		}
+		if err := collectOrphans(dbConn, time.Now(), orphans.PolicyFromEnv()); err == nil {
+			fmt.Println("success!")
+		}
+	},
+}
+
+func init() {
+	gcCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	if err := gcCmd.MarkFlagRequired("database"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(gcCmd)
+}
+
+func collectOrphans(db *sql.DB, now time.Time, policy orphans.Policy) error {
+	rssSrv := rss.New(db)
+
+	orphaned, restored, err := rssSrv.MarkOrphans(context.Background(), now)
+	if err != nil {
+		fmt.Println("could not mark orphaned rss feeds", err.Error())
+		return err
+	}
+	fmt.Printf("%d feeds lost their subscribers, %d were subscribed to again\n", orphaned, restored)
+
+	if policy.ArchiveAfter > 0 {
+		archived, err := rssSrv.Archive(context.Background(), now, now.Add(-policy.ArchiveAfter))
+		if err != nil {
+			fmt.Println("could not archive orphaned rss feeds", err.Error())
+			return err
+		}
+		fmt.Printf("archived %d feeds\n", archived)
+	}
+
+	if policy.DeleteAfter > 0 {
+		deleted, kept, err := rssSrv.DeleteOrphans(context.Background(), now.Add(-policy.DeleteAfter))
+		if err != nil {
+			fmt.Println("could not delete orphaned rss feeds", err.Error())
+			return err
+		}
+		fmt.Printf("deleted %d feeds, kept %d with starred posts\n", deleted, kept)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ogugu/internal/config"
+	"ogugu/internal/database"
+	"ogugu/internal/models"
+	"ogugu/internal/repository/posts"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete posts past their retention",
+	Long: `Delete posts older than RETENTION_MAX_AGE and posts beyond the newest
+RETENTION_MAX_ITEMS of their feed, or the limits a feed overrides them with.
+Starred posts are always kept. With --dry-run nothing is deleted and the
+posts that would be are counted per feed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := cmd.Flags().GetString("database")
+		dbConn, err := database.New("pgx", db)
+		if err != nil {
+			fmt.Println("unable to initialize database", err.Error())
+			os.Exit(1)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := prune(dbConn, time.Now(), dryRun); err == nil {
+			fmt.Println("success!")
+		}
+	},
+}
+
+func init() {
+	pruneCmd.Flags().StringP("database", "d", "", "database connection to run command against")
+	pruneCmd.Flags().Bool("dry-run", false, "report what would be deleted without deleting it")
+	if err := pruneCmd.MarkFlagRequired("database"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func retentionPolicy() models.RetentionPolicy {
+	return models.RetentionPolicy{
+		MaxAge:   config.Duration("RETENTION_MAX_AGE", 0),
+		MaxItems: config.Int("RETENTION_MAX_ITEMS", 0),
+	}
+}
+
+func prune(db *sql.DB, now time.Time, dryRun bool) error {
+	reports, err := posts.New(db).Prune(context.Background(), retentionPolicy(), now, dryRun)
+	if err != nil {
+		fmt.Println("could not prune posts", err.Error())
+		return err
+	}
+
+	verb := "deleted"
+	if dryRun {
+		verb = "would delete"
+	}
+	total := 0
+	for _, report := range reports {
+		fmt.Printf("%s %d posts of %s (%s)\n", verb, report.Posts, report.Title, report.RssID)
+		total += report.Posts
+	}
+	fmt.Printf("%s %d posts in total\n", verb, total)
+	return nil
+}
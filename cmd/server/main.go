@@ -18,7 +18,9 @@ import (
 	_ "ogugu/docs"
 	"ogugu/internal/database"
 	"ogugu/internal/database/cache"
+	"ogugu/internal/mailer"
 	"ogugu/internal/router"
+	"ogugu/internal/session"
 	"ogugu/internal/telemetry"
 )
 
@@ -32,6 +34,11 @@ import (
 // @in							header
 // @name						Authorization
 // @description				Enter your auth token in the format **Bearer &lt;token&gt;**
+//
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				A personal api key starting with ogk_
 func main() {
 	dotenv.Config()
 
@@ -46,12 +53,22 @@ func main() {
 		log.Error("unable to initialize cache store")
 		return
 	}
+	sessions, err := session.New(rds, session.ConfigFromEnv())
+	if err != nil {
+		log.Error("unable to initialize session backend", zap.Error(err))
+		return
+	}
+	mail, err := mailer.New(log)
+	if err != nil {
+		log.Error("unable to initialize mailer", zap.Error(err))
+		return
+	}
 
-	InitServer(db, rds, log)
+	InitServer(db, rds, sessions, mail, log)
 }
 
-func InitServer(db *sql.DB, rds *redis.Client, log *zap.Logger) {
-	r := router.Routes(db, rds, log)
+func InitServer(db *sql.DB, rds *redis.Client, sessions session.Backend, mail mailer.Mailer, log *zap.Logger) {
+	r := router.Routes(db, rds, sessions, mail, log)
 
 	log.Info("Server is running on port 8080")
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
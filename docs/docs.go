@@ -15,51 +15,80 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/feed": {
-            "get": {
-                "description": "Retrieve all RSS Feeds in the database.",
+        "/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "turn two-factor authentication off with a TOTP or recovery code",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "rss"
+                    "account"
                 ],
-                "summary": "Find all RSS feeds",
-                "responses": {
-                    "200": {
-                        "description": "RSS Feeds found",
+                "summary": "disable two-factor",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/response.RssFeeds"
+                            "$ref": "#/definitions/models.TwoFactorCodeBody"
                         }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "404": {
-                        "description": "RSS Feed not found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "500": {
-                        "description": "An error occured on the server",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "An error occured",
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/2fa/enable": {
             "post": {
-                "description": "Create a new RSS feed by providing the feed's name and link.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "confirm enrollment with a code from the authenticator app and receive recovery codes",
                 "consumes": [
                     "application/json"
                 ],
@@ -67,41 +96,53 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rss"
+                    "account"
                 ],
-                "summary": "Create a new RSS feed",
+                "summary": "enable two-factor",
                 "parameters": [
                     {
-                        "description": "Create a new RSS feed",
+                        "description": "body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateRssBody"
+                            "$ref": "#/definitions/models.TwoFactorCodeBody"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "RSS Feed created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.RssFeed"
+                            "$ref": "#/definitions/response.RecoveryCodes"
                         }
                     },
                     "400": {
-                        "description": "Invalid or malformed request body",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "500": {
-                        "description": "An error occured on the server",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "An error occured",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
@@ -109,129 +150,117 @@ const docTemplate = `{
                 }
             }
         },
-        "/feed/{id}": {
-            "get": {
-                "description": "Retrieve an existing RSS feed using its unique ID.",
+        "/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "generate a TOTP secret to add to an authenticator app. Two-factor is not enabled until a code is confirmed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "rss"
-                ],
-                "summary": "Find an RSS feed by its ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "ID of the RSS feed to retrieve",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "account"
                 ],
+                "summary": "start two-factor enrollment",
                 "responses": {
                     "200": {
-                        "description": "RSS Feed found",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.RssFeed"
+                            "$ref": "#/definitions/response.TwoFactorSetup"
                         }
                     },
-                    "400": {
-                        "description": "Invalid or malformed request body",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "404": {
-                        "description": "RSS Feed not found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "500": {
-                        "description": "An error occured on the server",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "An error occured",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete an existing RSS feed using its unique ID.",
+            }
+        },
+        "/2fa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "replace every recovery code with a new set",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "rss"
+                    "account"
                 ],
-                "summary": "Delete an RSS feed by its ID",
+                "summary": "regenerate recovery codes",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID of the RSS feed to retrieve",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorCodeBody"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "RSS Feed deleted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Response"
+                            "$ref": "#/definitions/response.RecoveryCodes"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "404": {
-                        "description": "RSS Feed not found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "500": {
-                        "description": "An error occured on the server",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "An error occured",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
-                    }
-                }
-            }
-        },
-        "/posts": {
-            "get": {
-                "description": "get all posts",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "posts"
-                ],
-                "summary": "get all posts",
-                "responses": {
-                    "200": {
-                        "description": "Posts found",
-                        "schema": {
-                            "$ref": "#/definitions/response.Posts"
-                        }
                     },
-                    "default": {
-                        "description": "Unable to get posts",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
@@ -239,50 +268,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}": {
+        "/apikeys": {
             "get": {
-                "description": "get a post by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "list the api keys of the current user",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
-                ],
-                "summary": "get a post",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "apikeys"
                 ],
+                "summary": "list api keys",
                 "responses": {
                     "200": {
-                        "description": "Post with ID found",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Post"
+                            "$ref": "#/definitions/response.APIKeys"
                         }
                     },
-                    "404": {
-                        "description": "Post with ID not found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "Unable to get post with id",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            }
-        },
-        "/signin": {
+            },
             "post": {
-                "description": "signin to an existing account",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "create a named api key. The key is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -290,25 +324,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "account"
+                    "apikeys"
                 ],
-                "summary": "sign in",
+                "summary": "create an api key",
                 "parameters": [
                     {
-                        "description": "body",
+                        "description": "api key name and scope",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SigninBody"
+                            "$ref": "#/definitions/models.CreateAPIKeyBody"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/response.UserWithAuth"
+                            "$ref": "#/definitions/response.APIKeyWithSecret"
                         }
                     },
                     "400": {
@@ -317,6 +351,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -326,39 +372,54 @@ const docTemplate = `{
                 }
             }
         },
-        "/signout": {
+        "/apikeys/{id}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "sign out from current session",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "revoke an api key belonging to the current user",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "account"
+                    "apikeys"
                 ],
-                "summary": "sign out",
-                "responses": {
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
+                "summary": "revoke an api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
-                    "default": {
-                        "description": "",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
@@ -366,35 +427,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/signup": {
-            "post": {
-                "description": "create a new account",
-                "consumes": [
-                    "application/json"
-                ],
+        "/digest/unsubscribe": {
+            "get": {
+                "description": "turn off the digest of the user the token from a digest email belongs to",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "account"
+                    "digest"
                 ],
-                "summary": "sign up",
+                "summary": "unsubscribe from the digest",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "unsubscribe token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CreateUserBody"
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
+                }
+            },
+            "post": {
+                "description": "turn off the digest of the user the token from a digest email belongs to",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "digest"
+                ],
+                "summary": "unsubscribe from the digest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "unsubscribe token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.User"
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "400": {
@@ -403,6 +504,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -412,45 +519,68 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscriptions": {
+        "/feed": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "get current user's subscriptions",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Search feeds and sort them by popularity, recent activity or title. Every feed carries its subscriber count and posting frequency.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscription"
+                    "rss"
+                ],
+                "summary": "Browse the feed directory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "text to find in the title, description or links",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only feeds in this language, e.g. en or en-us",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only feeds with posts in this category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "subscribers",
+                            "recent",
+                            "title"
+                        ],
+                        "type": "string",
+                        "description": "sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
                 ],
-                "summary": "get subscriptions",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "RSS Feeds found",
                         "schema": {
-                            "$ref": "#/definitions/response.Subscription"
+                            "$ref": "#/definitions/response.DirectoryFeeds"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "An error occured on the server",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "default": {
-                        "description": "",
+                        "description": "An error occured",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
@@ -458,12 +588,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "subscribe to an rss feed",
+                "description": "Create a new RSS feed by providing the feed's name and link. Links to a feed that exists, after following redirects and ignoring the scheme, \"www.\" and trailing slashes, return that feed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -471,198 +596,2289 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscription"
+                    "rss"
                 ],
-                "summary": "subscribe",
+                "summary": "Create a new RSS feed",
                 "parameters": [
                     {
-                        "description": "body",
+                        "description": "Create a new RSS feed",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SubscriptionBody"
+                            "$ref": "#/definitions/models.CreateRssBody"
                         }
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "RSS Feed exists",
+                        "schema": {
+                            "$ref": "#/definitions/response.RssFeed"
+                        }
+                    },
                     "201": {
-                        "description": "Created",
+                        "description": "RSS Feed created",
                         "schema": {
-                            "$ref": "#/definitions/response.Subscription"
+                            "$ref": "#/definitions/response.RssFeed"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid or malformed request body",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "An error occured on the server",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "default": {
-                        "description": "",
+                        "description": "An error occured",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "unsubscribe from an rss feed",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/feed/{id}": {
+            "get": {
+                "description": "Retrieve an existing RSS feed using its unique ID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscription"
+                    "rss"
                 ],
-                "summary": "unsubscribe",
+                "summary": "Find an RSS feed by its ID",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.SubscriptionBody"
-                        }
+                        "type": "string",
+                        "description": "ID of the RSS feed to retrieve",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "RSS Feed found",
+                        "schema": {
+                            "$ref": "#/definitions/response.RssFeed"
+                        }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid or malformed request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "RSS Feed not found",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "An error occured on the server",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "default": {
-                        "description": "",
+                        "description": "An error occured",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            }
-        },
-        "/subscriptions/posts": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "get posts from feed that user is subscribed to",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "Delete an existing RSS feed using its unique ID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscription"
+                    "rss"
+                ],
+                "summary": "Delete an RSS feed by its ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the RSS feed to retrieve",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "get posts",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "RSS Feed deleted",
                         "schema": {
-                            "$ref": "#/definitions/response.FeedPosts"
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "RSS Feed not found",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "An error occured on the server",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     },
                     "default": {
-                        "description": "",
+                        "description": "An error occured",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateRssBody": {
+        },
+        "/feed/{id}/full-text": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "When enabled, the worker downloads the page of every new post and stores the extracted article. Feeds are shared by every subscriber, so only admins can change this.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rss"
+                ],
+                "summary": "Toggle full text extraction for an RSS feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the RSS feed",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Full text setting",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateFullTextBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "RSS Feed updated",
+                        "schema": {
+                            "$ref": "#/definitions/response.RssFeed"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or malformed request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins can toggle full text extraction",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "RSS Feed not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "An error occured on the server",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "An error occured",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/feed/{id}/icon": {
+            "get": {
+                "description": "Serve the favicon of the feed's site, cached by the worker.",
+                "produces": [
+                    "image/png",
+                    "image/gif",
+                    "image/jpeg",
+                    "image/webp",
+                    "image/x-icon"
+                ],
+                "tags": [
+                    "rss"
+                ],
+                "summary": "Get the icon of an RSS feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the RSS feed",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Feed icon",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Icon unchanged"
+                    },
+                    "404": {
+                        "description": "RSS Feed or icon not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "An error occured on the server",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/feed/{id}/retention": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Null limits fall back to the global retention policy and zero limits keep posts forever.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rss"
+                ],
+                "summary": "Get the retention policy of an RSS feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the RSS feed",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Retention policy",
+                        "schema": {
+                            "$ref": "#/definitions/response.FeedRetention"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "RSS Feed not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "An error occured on the server",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Keep posts of the feed for at most max_age_days days and at most max_items posts. Starred posts are always kept. Feeds are shared by every subscriber, so only admins can change this.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rss"
+                ],
+                "summary": "Set the retention policy of an RSS feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the RSS feed",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retention policy",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateRetentionBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Retention policy updated",
+                        "schema": {
+                            "$ref": "#/definitions/response.FeedRetention"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or malformed request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Only admins can change the retention policy",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "RSS Feed not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "An error occured on the server",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/filters": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "list the filter rules of the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "list filter rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.FilterRules"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "hide, mark read or star posts whose title, description, author, category or feed matches a keyword or regex.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "create a filter rule",
+                "parameters": [
+                    {
+                        "description": "rule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateFilterRuleBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.FilterRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/filters/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "delete a filter rule belonging to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "delete a filter rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/digest": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get when the current user is emailed a digest of their unread posts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "digest"
+                ],
+                "summary": "get digest settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.DigestSettings"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "turn the email digest off or send it daily or weekly at an hour of the user's timezone",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "digest"
+                ],
+                "summary": "update digest settings",
+                "parameters": [
+                    {
+                        "description": "digest schedule",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateDigestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.DigestSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/digest/preview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "render the digest the current user would be sent now, without sending it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "digest"
+                ],
+                "summary": "preview the digest",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.DigestPreview"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/callback": {
+            "get": {
+                "description": "complete sign in with the identity provider, creating an account on first sign in. Users with two-factor enabled get a pending token to finish at /signin/2fa.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "identity provider callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.UserWithAuth"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/response.PendingTwoFactor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/login": {
+            "get": {
+                "description": "redirect to the configured OpenID Connect provider to sign in",
+                "tags": [
+                    "account"
+                ],
+                "summary": "sign in with the identity provider",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "issue a refresh token on sign in",
+                        "name": "refresh_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "email a single-use password reset link to the account owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "forgot password",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ForgotPasswordBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "set a new password using a token from the password reset email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "reset password",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResetPasswordBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts": {
+            "get": {
+                "description": "get all posts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "get all posts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only posts by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only posts in this category",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Posts found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Posts"
+                        }
+                    },
+                    "default": {
+                        "description": "Unable to get posts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}": {
+            "get": {
+                "description": "get a post by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "get a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Post with ID found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Post"
+                        }
+                    },
+                    "404": {
+                        "description": "Post with ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "Unable to get post with id",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/state": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the signed in user's read and star marks on a post. Omitted marks are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Mark a post read or starred",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Read and star marks",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePostStateBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Post state updated",
+                        "schema": {
+                            "$ref": "#/definitions/response.PostState"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or malformed request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Post with ID not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "An error occured on the server",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "An error occured",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "list the current user's active sessions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "list sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Sessions"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "revoke every session belonging to the current user, including this one",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "sign out everywhere",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "sign out a single session belonging to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/signin": {
+            "post": {
+                "description": "signin to an existing account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "sign in",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SigninBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.UserWithAuth"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/response.PendingTwoFactor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/signin/2fa": {
+            "post": {
+                "description": "finish a sign in that requires two-factor authentication with a TOTP or recovery code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "complete sign in",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorSigninBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.UserWithAuth"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/signout": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "sign out from current session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "sign out",
+                "responses": {
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/signup": {
+            "post": {
+                "description": "create a new account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "sign up",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateUserBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get current user's subscriptions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscription"
+                ],
+                "summary": "get subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "subscribe to an rss feed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscription"
+                ],
+                "summary": "subscribe",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscriptionBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "unsubscribe from an rss feed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscription"
+                ],
+                "summary": "unsubscribe",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscriptionBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/posts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get posts from feed that user is subscribed to, without posts hidden by the user's filter rules.\nA story published by several feeds appears once, listing the other feeds in also_in.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscription"
+                ],
+                "summary": "get posts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only posts by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only posts in this category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ranked"
+                        ],
+                        "type": "string",
+                        "description": "ranked orders posts by recency, reading history and feed frequency",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.FeedPosts"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "exchange a refresh token for a new access token and refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "refresh access token",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify-email": {
+            "get": {
+                "description": "confirm ownership of the account's email address",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "verify email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "verification token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify-email/resend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "send a new verification email to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "resend verification email",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.APIKeyWithSecret": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/models.APIKey"
+                },
+                "key": {
+                    "description": "Key is only ever returned when the key is created.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AlsoIn": {
+            "type": "object",
+            "properties": {
+                "feed_title": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "post_id": {
+                    "type": "string"
+                },
+                "rss_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateAPIKeyBody": {
+            "type": "object",
+            "required": [
+                "name",
+                "scope"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scope": {
+                    "type": "string",
+                    "enum": [
+                        "read",
+                        "read_write"
+                    ]
+                }
+            }
+        },
+        "models.CreateFilterRuleBody": {
+            "type": "object",
+            "required": [
+                "action",
+                "field",
+                "match",
+                "pattern"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "hide",
+                        "read",
+                        "star"
+                    ]
+                },
+                "field": {
+                    "type": "string",
+                    "enum": [
+                        "title",
+                        "description",
+                        "text",
+                        "author",
+                        "category",
+                        "feed"
+                    ]
+                },
+                "match": {
+                    "type": "string",
+                    "enum": [
+                        "keyword",
+                        "regex"
+                    ]
+                },
+                "pattern": {
+                    "type": "string",
+                    "maxLength": 256
+                }
+            }
+        },
+        "models.CreateRssBody": {
+            "type": "object",
+            "required": [
+                "link"
+            ],
+            "properties": {
+                "link": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateUserBody": {
+            "type": "object",
+            "required": [
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "avatar": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 75
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DigestPreview": {
+            "type": "object",
+            "properties": {
+                "html": {
+                    "type": "string"
+                },
+                "posts": {
+                    "type": "integer"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DigestSettings": {
+            "type": "object",
+            "properties": {
+                "frequency": {
+                    "type": "string"
+                },
+                "hour": {
+                    "type": "integer"
+                },
+                "last_sent_at": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DirectoryFeed": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "copyright": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "fetch_full_text": {
+                    "type": "boolean"
+                },
+                "fetched": {
+                    "type": "boolean"
+                },
+                "generator": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "last_modified": {
+                    "type": "string"
+                },
+                "last_post_at": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "orphaned_since": {
+                    "type": "string"
+                },
+                "podcast": {
+                    "$ref": "#/definitions/models.Podcast"
+                },
+                "posts_per_week": {
+                    "type": "number"
+                },
+                "rss_link": {
+                    "type": "string"
+                },
+                "subscribers": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Enclosure": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Episode": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "integer"
+                },
+                "episode": {
+                    "type": "integer"
+                },
+                "episode_type": {
+                    "type": "string"
+                },
+                "explicit": {
+                    "type": "boolean"
+                },
+                "season": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FeedRetention": {
+            "type": "object",
+            "properties": {
+                "max_age_days": {
+                    "type": "integer"
+                },
+                "max_items": {
+                    "type": "integer"
+                },
+                "rss_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FilterRule": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "match": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ForgotPasswordBody": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PendingTwoFactor": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "pending_token": {
+                    "type": "string"
+                },
+                "two_factor_required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Podcast": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "explicit": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Post": {
+            "type": "object",
+            "properties": {
+                "also_in": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AlsoIn"
+                    }
+                },
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cluster_id": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enclosures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Enclosure"
+                    }
+                },
+                "episode": {
+                    "$ref": "#/definitions/models.Episode"
+                },
+                "feed_title": {
+                    "type": "string"
+                },
+                "full_content": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "pubDate": {
+                    "type": "string"
+                },
+                "rss_id": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "thumbnail": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PostState": {
+            "type": "object",
+            "properties": {
+                "post_id": {
+                    "type": "string"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "starred_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.RefreshBody": {
             "type": "object",
             "required": [
-                "link"
+                "refresh_token"
             ],
             "properties": {
-                "link": {
+                "refresh_token": {
                     "type": "string"
                 }
             }
         },
-        "models.CreateUserBody": {
+        "models.ResetPasswordBody": {
             "type": "object",
             "required": [
-                "email",
                 "password",
-                "username"
+                "token"
             ],
             "properties": {
-                "avatar": {
-                    "type": "string"
-                },
-                "email": {
-                    "type": "string"
-                },
                 "password": {
                     "type": "string",
                     "maxLength": 75
                 },
-                "username": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "models.Post": {
+        "models.RssFeed": {
             "type": "object",
             "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "copyright": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "description": {
                     "type": "string"
                 },
+                "fetch_full_text": {
+                    "type": "boolean"
+                },
+                "fetched": {
+                    "type": "boolean"
+                },
+                "generator": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "image": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "last_modified": {
+                    "type": "string"
+                },
                 "link": {
                     "type": "string"
                 },
-                "pubDate": {
+                "orphaned_since": {
+                    "type": "string"
+                },
+                "podcast": {
+                    "$ref": "#/definitions/models.Podcast"
+                },
+                "rss_link": {
                     "type": "string"
                 },
                 "title": {
@@ -673,31 +2889,34 @@ const docTemplate = `{
                 }
             }
         },
-        "models.RssFeed": {
+        "models.Session": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "absolute_expires_at": {
                     "type": "string"
                 },
-                "description": {
+                "created_at": {
                     "type": "string"
                 },
-                "fetched": {
+                "current": {
                     "type": "boolean"
                 },
+                "expires_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
-                "last_modified": {
+                "ip": {
                     "type": "string"
                 },
-                "link": {
+                "last_seen_at": {
                     "type": "string"
                 },
-                "title": {
+                "user_agent": {
                     "type": "string"
                 },
-                "updated_at": {
+                "user_id": {
                     "type": "string"
                 }
             }
@@ -715,6 +2934,10 @@ const docTemplate = `{
                 "password": {
                     "type": "string",
                     "maxLength": 75
+                },
+                "refresh_token": {
+                    "description": "RefreshToken requests a short-lived access token paired with a\nrotating refresh token instead of a sliding session.",
+                    "type": "boolean"
                 }
             }
         },
@@ -749,6 +2972,127 @@ const docTemplate = `{
                 }
             }
         },
+        "models.TokenPair": {
+            "type": "object",
+            "properties": {
+                "auth_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorCodeBody": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32
+                }
+            }
+        },
+        "models.TwoFactorSetup": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TwoFactorSigninBody": {
+            "type": "object",
+            "required": [
+                "code",
+                "pending_token"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32
+                },
+                "pending_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdateDigestBody": {
+            "type": "object",
+            "required": [
+                "frequency",
+                "hour",
+                "timezone"
+            ],
+            "properties": {
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "daily",
+                        "weekly"
+                    ]
+                },
+                "hour": {
+                    "type": "integer",
+                    "maximum": 23,
+                    "minimum": 0
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0
+                }
+            }
+        },
+        "models.UpdateFullTextBody": {
+            "type": "object",
+            "required": [
+                "fetch_full_text"
+            ],
+            "properties": {
+                "fetch_full_text": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdatePostStateBody": {
+            "type": "object",
+            "properties": {
+                "read": {
+                    "type": "boolean"
+                },
+                "starred": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateRetentionBody": {
+            "type": "object",
+            "properties": {
+                "max_age_days": {
+                    "type": "integer",
+                    "maximum": 36500,
+                    "minimum": 0
+                },
+                "max_items": {
+                    "type": "integer",
+                    "maximum": 1000000,
+                    "minimum": 0
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
@@ -761,6 +3105,9 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
+                "email_verified_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -778,11 +3125,78 @@ const docTemplate = `{
                 "auth_token": {
                     "type": "string"
                 },
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
                 "user": {
                     "$ref": "#/definitions/models.User"
                 }
             }
         },
+        "response.APIKeyWithSecret": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.APIKeyWithSecret"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.APIKeys": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIKey"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.DigestPreview": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.DigestPreview"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.DigestSettings": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.DigestSettings"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.DirectoryFeeds": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DirectoryFeed"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "response.FeedPosts": {
             "type": "object",
             "properties": {
@@ -797,6 +3211,53 @@ const docTemplate = `{
                 }
             }
         },
+        "response.FeedRetention": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.FeedRetention"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.FilterRule": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.FilterRule"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.FilterRules": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FilterRule"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.PendingTwoFactor": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.PendingTwoFactor"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "response.Post": {
             "type": "object",
             "properties": {
@@ -808,6 +3269,17 @@ const docTemplate = `{
                 }
             }
         },
+        "response.PostState": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.PostState"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "response.Posts": {
             "type": "object",
             "properties": {
@@ -822,6 +3294,17 @@ const docTemplate = `{
                 }
             }
         },
+        "response.RecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.RecoveryCodes"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "response.Response": {
             "type": "object",
             "properties": {
@@ -842,13 +3325,13 @@ const docTemplate = `{
                 }
             }
         },
-        "response.RssFeeds": {
+        "response.Sessions": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.RssFeed"
+                        "$ref": "#/definitions/models.Session"
                     }
                 },
                 "message": {
@@ -867,6 +3350,28 @@ const docTemplate = `{
                 }
             }
         },
+        "response.TokenPair": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.TokenPair"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.TwoFactorSetup": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.TwoFactorSetup"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "response.User": {
             "type": "object",
             "properties": {
@@ -891,6 +3396,12 @@ const docTemplate = `{
         }
     },
     "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "A personal api key starting with ogk_",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "BearerAuth": {
             "description": "Enter your auth token in the format **Bearer \u0026lt;token\u0026gt;**",
             "type": "apiKey",
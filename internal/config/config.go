@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// String returns the value of the environment variable named by key,
+// or def when it is unset or empty.
+func String(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// Int returns the integer value of the environment variable named by key,
+// or def when it is unset or cannot be parsed.
+func Int(key string, def int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// Bool returns the boolean value of the environment variable named by key,
+// or def when it is unset or cannot be parsed.
+func Bool(key string, def bool) bool {
+	val, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// Duration returns the duration value (e.g. "15m", "72h") of the environment
+// variable named by key, or def when it is unset or cannot be parsed.
+func Duration(key string, def time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return val
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("unset values fall back to defaults", func(t *testing.T) {
+		require.Equal(t, "default", String("OGUGU_TEST_UNSET", "default"))
+		require.Equal(t, 4, Int("OGUGU_TEST_UNSET", 4))
+		require.Equal(t, true, Bool("OGUGU_TEST_UNSET", true))
+		require.Equal(t, time.Minute, Duration("OGUGU_TEST_UNSET", time.Minute))
+	})
+
+	t.Run("set values are parsed", func(t *testing.T) {
+		t.Setenv("OGUGU_TEST_STRING", "value")
+		t.Setenv("OGUGU_TEST_INT", "12")
+		t.Setenv("OGUGU_TEST_BOOL", "false")
+		t.Setenv("OGUGU_TEST_DURATION", "90s")
+
+		require.Equal(t, "value", String("OGUGU_TEST_STRING", "default"))
+		require.Equal(t, 12, Int("OGUGU_TEST_INT", 4))
+		require.Equal(t, false, Bool("OGUGU_TEST_BOOL", true))
+		require.Equal(t, 90*time.Second, Duration("OGUGU_TEST_DURATION", time.Minute))
+	})
+
+	t.Run("malformed values fall back to defaults", func(t *testing.T) {
+		t.Setenv("OGUGU_TEST_INT", "twelve")
+		t.Setenv("OGUGU_TEST_DURATION", "soon")
+
+		require.Equal(t, 4, Int("OGUGU_TEST_INT", 4))
+		require.Equal(t, time.Minute, Duration("OGUGU_TEST_DURATION", time.Minute))
+	})
+}
@@ -0,0 +1,168 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/models"
+	"ogugu/internal/repository/apikeys"
+	"ogugu/internal/secure"
+)
+
+var (
+	tracer   = otel.Tracer("API Keys Controller")
+	Validate = validator.New()
+)
+
+// Prefix marks a bearer token as an api key rather than a session token.
+const Prefix = "ogk_"
+
+// prefixLength is how much of a key is kept in clear so users can tell
+// their keys apart.
+const prefixLength = len(Prefix) + 8
+
+type Controller struct {
+	log        *zap.Logger
+	apiKeyRepo *apikeys.Repository
+}
+
+func New(l *zap.Logger, k *apikeys.Repository) *Controller {
+	return &Controller{
+		log:        l,
+		apiKeyRepo: k,
+	}
+}
+
+// @Summary		create an api key
+// @Description	create a named api key. The key is only returned once.
+// @Security		BearerAuth
+// @Tags			apikeys
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.CreateAPIKeyBody	true	"api key name and scope"
+// @Success		201		{object}	response.APIKeyWithSecret
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/apikeys [post]
+func (c *Controller) Create(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "create api key")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage API keys", http.StatusForbidden, c.log)
+		return
+	}
+
+	if r.Body == nil {
+		response.Error(w, "Request body cannot be empty", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.CreateAPIKeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, "Request body is not valid json", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err := Validate.Struct(body); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	token, err := secure.Token(32)
+	if err != nil {
+		c.log.Error("could not generate api key", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	secret := Prefix + token
+
+	key, err := c.apiKeyRepo.Create(
+		spanctx, ulid.Make().String(), sess.UserID, secret[:prefixLength], secure.Hash(secret), body,
+	)
+	if err != nil {
+		c.log.Error("could not create api key", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	data := models.APIKeyWithSecret{APIKey: key, Key: secret}
+	response.Success(w, "API key created", http.StatusCreated, data, c.log)
+}
+
+// @Summary		list api keys
+// @Description	list the api keys of the current user
+// @Security		BearerAuth
+// @Tags			apikeys
+// @Produce		json
+// @Success		200		{object}	response.APIKeys
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/apikeys [get]
+func (c *Controller) List(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "list api keys")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage API keys", http.StatusForbidden, c.log)
+		return
+	}
+
+	keys, err := c.apiKeyRepo.ListByUser(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not list api keys", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Resources found", http.StatusOK, keys, c.log)
+}
+
+// @Summary		revoke an api key
+// @Description	revoke an api key belonging to the current user
+// @Security		BearerAuth
+// @Tags			apikeys
+// @Produce		json
+// @Param			id		path	string	true	"API key ID"
+// @Success		204
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		404		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/apikeys/{id} [delete]
+func (c *Controller) Revoke(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "revoke api key")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage API keys", http.StatusForbidden, c.log)
+		return
+	}
+
+	id := r.PathValue("id")
+	n, err := c.apiKeyRepo.Delete(spanctx, sess.UserID, id)
+	if err != nil {
+		c.log.Error("could not revoke api key", zap.String("id", id), zap.Error(err))
+		response.Error(w, "An error occured while revoking the api key", http.StatusInternalServerError, c.log)
+		return
+	}
+	if n == 0 {
+		response.Error(w, "api key with id not found", http.StatusNotFound, c.log)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
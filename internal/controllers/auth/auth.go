@@ -1,8 +1,18 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -12,10 +22,18 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
+	"ogugu/internal/config"
+	"ogugu/internal/controllers/common/request"
 	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/lockout"
+	"ogugu/internal/mailer"
 	"ogugu/internal/models"
 	"ogugu/internal/repository/auth"
+	"ogugu/internal/repository/tokens"
+	"ogugu/internal/repository/twofactor"
 	"ogugu/internal/repository/users"
+	"ogugu/internal/secure"
+	"ogugu/internal/session"
 )
 
 var (
@@ -24,18 +42,52 @@ var (
 )
 
 type Controller struct {
-	cache    *redis.Client
-	log      *zap.Logger
-	userRepo *users.Repository
-	authRepo *auth.Repository
+	sessions      session.Backend
+	cache         *redis.Client
+	log           *zap.Logger
+	mail          mailer.Mailer
+	userRepo      *users.Repository
+	authRepo      *auth.Repository
+	tokenRepo     *tokens.Repository
+	twoFactorRepo *twofactor.Repository
+	limiter       *lockout.Limiter
+	bcryptCost    int
+	// dummyHash is compared against when an account has no password so a
+	// failed sign in takes as long whether or not the email is registered.
+	dummyHash func() []byte
 }
 
-func New(c *redis.Client, l *zap.Logger, u *users.Repository, a *auth.Repository) *Controller {
+func New(
+	s session.Backend,
+	cache *redis.Client,
+	l *zap.Logger,
+	m mailer.Mailer,
+	u *users.Repository,
+	a *auth.Repository,
+	t *tokens.Repository,
+	tf *twofactor.Repository,
+	lim *lockout.Limiter,
+) *Controller {
+	cost := config.Int("BCRYPT_COST", 12)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+
 	return &Controller{
-		cache:    c,
-		log:      l,
-		userRepo: u,
-		authRepo: a,
+		sessions:      s,
+		cache:         cache,
+		log:           l,
+		mail:          m,
+		userRepo:      u,
+		authRepo:      a,
+		tokenRepo:     t,
+		twoFactorRepo: tf,
+		limiter:       lim,
+		bcryptCost:    cost,
+		dummyHash: sync.OnceValue(func() []byte {
+			hash, _ := bcrypt.GenerateFromPassword([]byte("ogugu dummy password"), cost)
+			return hash
+		}),
 	}
 }
 
@@ -47,6 +99,7 @@ func New(c *redis.Client, l *zap.Logger, u *users.Repository, a *auth.Repository
 // @Produce		json
 // @Sucess			204
 // @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
 // @Failure		500		{object}	response.Response
 // @Failure		default	{object}	response.Response
 // @Router			/signout [delete]
@@ -55,9 +108,13 @@ func (c *Controller) Signout(w http.ResponseWriter, r *http.Request) {
 	defer span.End()
 
 	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage sessions", http.StatusForbidden, c.log)
+		return
+	}
 
-	err := c.cache.SetEx(spanctx, sess.ID, "", time.Second).Err()
-	if err != nil {
+	err := c.sessions.Delete(spanctx, sess.UserID, sess.ID)
+	if err != nil && !errors.Is(err, session.ErrNotFound) {
 		c.log.Error("could not delete user session", zap.Error(err))
 		response.Error(w, "An error occured while deleting the session", http.StatusInternalServerError, c.log)
 		return
@@ -67,6 +124,112 @@ func (c *Controller) Signout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary		list sessions
+// @Description	list the current user's active sessions
+// @Security		BearerAuth
+// @Tags			account
+// @Produce		json
+// @Success		200		{object}	response.Sessions
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/sessions [get]
+func (c *Controller) ListSessions(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "list sessions")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage sessions", http.StatusForbidden, c.log)
+		return
+	}
+
+	sessions, err := c.sessions.List(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not list user sessions", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == sess.ID
+	}
+
+	response.Success(w, "Resources found", http.StatusOK, sessions, c.log)
+}
+
+// @Summary		revoke a session
+// @Description	sign out a single session belonging to the current user
+// @Security		BearerAuth
+// @Tags			account
+// @Produce		json
+// @Param			id		path	string	true	"Session ID"
+// @Success		204
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		404		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/sessions/{id} [delete]
+func (c *Controller) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "revoke session")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage sessions", http.StatusForbidden, c.log)
+		return
+	}
+
+	id := r.PathValue("id")
+	err := c.sessions.Delete(spanctx, sess.UserID, id)
+	if err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			response.Error(w, "session with id not found", http.StatusNotFound, c.log)
+			return
+		}
+		c.log.Error("could not revoke session", zap.String("id", id), zap.Error(err))
+		response.Error(w, "An error occured while deleting the session", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary		sign out everywhere
+// @Description	revoke every session belonging to the current user, including this one
+// @Security		BearerAuth
+// @Tags			account
+// @Produce		json
+// @Success		204
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/sessions [delete]
+func (c *Controller) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "revoke all sessions")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage sessions", http.StatusForbidden, c.log)
+		return
+	}
+
+	_, err := c.sessions.DeleteAll(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not revoke sessions", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "An error occured while deleting the sessions", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // @Summary		sign in
 // @Description	signin to an existing account
 // @Tags			account
@@ -74,7 +237,10 @@ func (c *Controller) Signout(w http.ResponseWriter, r *http.Request) {
 // @Produce		json
 // @Param			body	body		models.SigninBody	true	"body"
 // @Success		200		{object}	response.UserWithAuth
+// @Success		202		{object}	response.PendingTwoFactor
 // @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		429		{object}	response.Response
 // @Failure		500		{object}	response.Response
 // @Router			/signin [post]
 func (c *Controller) Signin(w http.ResponseWriter, r *http.Request) {
@@ -101,53 +267,170 @@ func (c *Controller) Signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	keys := []lockout.Key{
+		c.limiter.IP(request.ClientIP(r)),
+		c.limiter.Account(strings.ToLower(body.Email)),
+	}
+	retry, err := c.limiter.Locked(spanctx, keys...)
+	if err != nil {
+		c.log.Error("could not check sign in lockout", zap.Error(err))
+		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
+		return
+	}
+	if retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		response.Error(w, "Too many failed sign in attempts, try again later", http.StatusTooManyRequests, c.log)
+		return
+	}
+
+	// unknown emails and accounts without a password still pay for a bcrypt
+	// comparison so response times don't reveal which emails are registered
+	hashpwd := c.dummyHash()
 	user, err := c.userRepo.GetUser(spanctx, "email", body.Email)
 	if err != nil {
 		c.log.Warn("user not found", zap.String("email", body.Email))
+	} else if stored, perr := c.authRepo.GetPasswordWithUserID(spanctx, user.ID); perr != nil {
+		c.log.Warn("could not get password", zap.String("email", body.Email), zap.Error(perr))
+		err = perr
+	} else {
+		hashpwd = []byte(stored)
+	}
+
+	if cerr := bcrypt.CompareHashAndPassword(hashpwd, []byte(body.Password)); cerr != nil || err != nil {
+		if err := c.limiter.Fail(spanctx, keys...); err != nil {
+			c.log.Error("could not record failed sign in", zap.Error(err))
+		}
 		response.Error(w, "Login Failed, check credentials", http.StatusUnauthorized, c.log)
 		return
 	}
 
-	hashpwd, err := c.authRepo.GetPasswordWithUserID(spanctx, user.ID)
+	if err := c.limiter.Reset(spanctx, keys[1]); err != nil {
+		c.log.Error("could not reset failed sign ins", zap.Error(err))
+	}
+	c.rehash(spanctx, user.ID, hashpwd, body.Password)
+
+	pending, required, err := c.StartTwoFactor(spanctx, user.ID, body.RefreshToken)
 	if err != nil {
-		c.log.Warn("could not get password", zap.String("email", body.Email), zap.Error(err))
-		response.Error(w, "Login Failed, check credentials", http.StatusUnauthorized, c.log)
+		c.log.Error("could not start two factor sign in", zap.String("userid", user.ID), zap.Error(err))
+		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
+		return
+	}
+	if required {
+		response.Success(w, "Two-factor authentication required", http.StatusAccepted, pending, c.log)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashpwd), []byte(body.Password))
-	if err != nil {
-		response.Error(w, "Login Failed, check credentials", http.StatusUnauthorized, c.log)
+	c.signin(spanctx, w, r, user, body.RefreshToken)
+}
+
+// rehash upgrades a password hash made with a lower cost than the current
+// setting. Failures are only logged since the user has already signed in.
+func (c *Controller) rehash(ctx context.Context, userID string, hash []byte, password string) {
+	cost, err := bcrypt.Cost(hash)
+	if err != nil || cost >= c.bcryptCost {
 		return
 	}
 
-	sessionid := ulid.Make().String()
-	session, err := json.Marshal(models.Session{
-		ID:         sessionid,
-		UserID:     user.ID,
-		CreatedAt:  time.Now(),
-		ExpiryTime: time.Now().Add(time.Hour * 24 * 3),
-	})
+	upgraded, err := bcrypt.GenerateFromPassword([]byte(password), c.bcryptCost)
 	if err != nil {
-		c.log.Error("unable to marshal session", zap.Error(err))
-		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
+		c.log.Error("could not rehash password", zap.String("userid", userID), zap.Error(err))
 		return
 	}
 
-	err = c.cache.Set(spanctx, sessionid, session, time.Second*259200).Err()
-	if err != nil && err != redis.Nil {
+	if _, err := c.authRepo.UpdatePassword(ctx, userID, string(upgraded)); err != nil {
+		c.log.Error("could not store rehashed password", zap.String("userid", userID), zap.Error(err))
+	}
+}
+
+// signin starts a session for user and writes it to w.
+func (c *Controller) signin(ctx context.Context, w http.ResponseWriter, r *http.Request, user models.User, refresh bool) {
+	meta := models.Session{
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IP:        request.ClientIP(r),
+	}
+
+	var data models.UserWithAuth
+	var sess models.Session
+	var err error
+	if refresh {
+		data.AuthToken, data.RefreshToken, sess, err = c.sessions.CreateWithRefresh(ctx, meta)
+	} else {
+		data.AuthToken, sess, err = c.sessions.Create(ctx, meta)
+	}
+	if err != nil {
 		c.log.Error("unable to create session", zap.Error(err))
 		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
 		return
 	}
 
-	data := models.UserWithAuth{
-		User:      user,
-		AuthToken: sessionid,
-	}
+	data.User = user
+	data.ExpiresAt = sess.ExpiryTime
 	response.Success(w, "Login Successful", http.StatusOK, data, c.log)
 }
 
+// @Summary		refresh access token
+// @Description	exchange a refresh token for a new access token and refresh token
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.RefreshBody	true	"body"
+// @Success		200		{object}	response.TokenPair
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/token/refresh [post]
+func (c *Controller) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "refresh token")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.RefreshBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	meta := models.Session{
+		UserAgent: r.UserAgent(),
+		IP:        request.ClientIP(r),
+	}
+	access, refresh, sess, err := c.sessions.Refresh(spanctx, body.RefreshToken, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrRefreshTokenReused):
+			c.log.Warn("refresh token reuse detected, token family revoked")
+			response.Error(w, "The refresh token is invalid", http.StatusUnauthorized, c.log)
+		case errors.Is(err, session.ErrNotFound):
+			response.Error(w, "The refresh token is invalid", http.StatusUnauthorized, c.log)
+		default:
+			c.log.Error("could not refresh session", zap.Error(err))
+			response.Error(w, "An error occured while refreshing the session", http.StatusInternalServerError, c.log)
+		}
+		return
+	}
+
+	data := models.TokenPair{
+		AuthToken:    access,
+		RefreshToken: refresh,
+		ExpiresAt:    sess.ExpiryTime,
+	}
+	response.Success(w, "Token refreshed", http.StatusOK, data, c.log)
+}
+
 // @Summary		sign up
 // @Description	create a new account
 // @Tags			account
@@ -190,7 +473,7 @@ func (c *Controller) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(body.Password), 4)
+	hashed, err := bcrypt.GenerateFromPassword([]byte(body.Password), c.bcryptCost)
 	if err != nil {
 		c.log.Error("password hashing failed", zap.Error(err))
 		response.Error(w, "An error occured while creating the user", http.StatusInternalServerError, c.log)
@@ -205,5 +488,273 @@ func (c *Controller) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = c.sendVerification(spanctx, user); err != nil {
+		c.log.Error("could not send verification email", zap.String("userid", user.ID), zap.Error(err))
+	}
+
 	response.Success(w, "Sign up successfull", http.StatusCreated, user, c.log)
 }
+
+// @Summary		verify email
+// @Description	confirm ownership of the account's email address
+// @Tags			account
+// @Produce		json
+// @Param			token	query		string	true	"verification token from the email"
+// @Success		200		{object}	response.User
+// @Failure		400		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/verify-email [get]
+func (c *Controller) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "verify email")
+	defer span.End()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, "verification token is missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	userID, err := c.tokenRepo.Consume(spanctx, tokens.PurposeEmailVerification, secure.Hash(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "The verification token is invalid or has expired", http.StatusBadRequest, c.log)
+			return
+		}
+		c.log.Error("could not consume verification token", zap.Error(err))
+		response.Error(w, "An error occured while verifying the email", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	user, err := c.userRepo.MarkEmailVerified(spanctx, userID)
+	if err != nil {
+		c.log.Error("could not mark email as verified", zap.String("userid", userID), zap.Error(err))
+		response.Error(w, "An error occured while verifying the email", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Email verified", http.StatusOK, user, c.log)
+}
+
+// @Summary		resend verification email
+// @Description	send a new verification email to the current user
+// @Security		BearerAuth
+// @Tags			account
+// @Produce		json
+// @Success		202		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		409		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/verify-email/resend [post]
+func (c *Controller) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "resend verification email")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+
+	user, err := c.userRepo.GetUserByID(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not get user", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "An error occured while sending the verification email", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		response.Error(w, "Email is already verified", http.StatusConflict, c.log)
+		return
+	}
+
+	if err = c.sendVerification(spanctx, user); err != nil {
+		c.log.Error("could not send verification email", zap.String("userid", user.ID), zap.Error(err))
+		response.Error(w, "An error occured while sending the verification email", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Verification email sent", http.StatusAccepted, nil, c.log)
+}
+
+func (c *Controller) sendVerification(ctx context.Context, user models.User) error {
+	ttl := config.Duration("EMAIL_VERIFICATION_TTL", time.Hour*24)
+	token, err := c.issueToken(ctx, user.ID, tokens.PurposeEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+
+	return c.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your ogugu email address",
+		Text: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. "+
+			"It expires in %s.\n\n%s\n",
+			user.Username, ttl, tokenURL(config.String("API_URL", "http://localhost:8080"), "/v1/verify-email", token)),
+	})
+}
+
+// @Summary		forgot password
+// @Description	email a single-use password reset link to the account owner
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.ForgotPasswordBody	true	"body"
+// @Success		202		{object}	response.Response
+// @Failure		400		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/password/forgot [post]
+func (c *Controller) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "forgot password")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.ForgotPasswordBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	// the same response is sent whether or not the account exists so the
+	// endpoint cannot be used to discover registered emails.
+	msg := "If an account with that email exists, a password reset link has been sent"
+
+	user, err := c.userRepo.GetUser(spanctx, "email", body.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			c.log.Error("could not get user", zap.Error(err))
+		}
+		response.Success(w, msg, http.StatusAccepted, nil, c.log)
+		return
+	}
+
+	token, err := c.issueToken(spanctx, user.ID, tokens.PurposePasswordReset,
+		config.Duration("PASSWORD_RESET_TTL", time.Hour))
+	if err != nil {
+		c.log.Error("could not issue password reset token", zap.Error(err))
+		response.Error(w, "An error occured while processing the request", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	link := tokenURL(config.String("APP_URL", "http://localhost:8080"), "/reset-password", token)
+	err = c.mail.Send(spanctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your ogugu password",
+		Text: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. "+
+			"It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not request a password reset you can ignore this email.\n",
+			user.Username, config.Duration("PASSWORD_RESET_TTL", time.Hour), link),
+	})
+	if err != nil {
+		c.log.Error("could not send password reset email", zap.Error(err))
+	}
+
+	response.Success(w, msg, http.StatusAccepted, nil, c.log)
+}
+
+// @Summary		reset password
+// @Description	set a new password using a token from the password reset email
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.ResetPasswordBody	true	"body"
+// @Success		200		{object}	response.Response
+// @Failure		400		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/password/reset [post]
+func (c *Controller) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "reset password")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.ResetPasswordBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	userID, err := c.tokenRepo.Consume(spanctx, tokens.PurposePasswordReset, secure.Hash(body.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "The reset token is invalid or has expired", http.StatusBadRequest, c.log)
+			return
+		}
+		c.log.Error("could not consume password reset token", zap.Error(err))
+		response.Error(w, "An error occured while resetting the password", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(body.Password), c.bcryptCost)
+	if err != nil {
+		c.log.Error("password hashing failed", zap.Error(err))
+		response.Error(w, "An error occured while resetting the password", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	n, err := c.authRepo.UpdatePassword(spanctx, userID, string(hashed))
+	if err == nil && n == 0 {
+		// accounts created through an identity provider have no password yet
+		err = c.authRepo.CreateAuth(spanctx, userID, string(hashed))
+	}
+	if err != nil {
+		c.log.Error("could not update password", zap.Error(err))
+		response.Error(w, "An error occured while resetting the password", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	_, err = c.tokenRepo.DeleteForUser(spanctx, userID, tokens.PurposePasswordReset)
+	if err != nil {
+		c.log.Warn("could not clear password reset tokens", zap.Error(err))
+	}
+
+	// anyone holding a session obtained with the old password is signed out
+	if _, err = c.sessions.DeleteAll(spanctx, userID); err != nil {
+		c.log.Error("could not revoke sessions after password reset", zap.String("userid", userID), zap.Error(err))
+	}
+
+	response.Success(w, "Password reset successful", http.StatusOK, nil, c.log)
+}
+
+// issueToken replaces any outstanding token the user holds for purpose with a
+// fresh one and returns the plain token. Only its hash is stored.
+func (c *Controller) issueToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	if _, err := c.tokenRepo.DeleteForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := secure.Token(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.tokenRepo.Create(ctx, ulid.Make().String(), userID, purpose, secure.Hash(token), time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// tokenURL builds a link to path on base carrying token as a query parameter.
+func tokenURL(base, path, token string) string {
+	return base + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,530 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"ogugu/internal/config"
+	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/lockout"
+	"ogugu/internal/models"
+	"ogugu/internal/secure"
+	"ogugu/internal/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxTwoFactorAttempts is how many codes can be tried against a single
+	// pending sign in before the user has to enter their password again.
+	maxTwoFactorAttempts = 5
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// pendingSignin is kept in redis between the password and code steps.
+type pendingSignin struct {
+	UserID       string `json:"user_id"`
+	RefreshToken bool   `json:"refresh_token"`
+}
+
+func pendingKey(hash string) string {
+	return "2fa:pending:" + hash
+}
+
+func pendingAttemptsKey(hash string) string {
+	return "2fa:pending:" + hash + ":attempts"
+}
+
+// StartTwoFactor starts a pending sign in when userID has two-factor
+// enabled. required is false when the user can be signed in straight away.
+func (c *Controller) StartTwoFactor(ctx context.Context, userID string, refresh bool) (pending models.PendingTwoFactor, required bool, err error) {
+	tf, err := c.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PendingTwoFactor{}, false, nil
+		}
+		return models.PendingTwoFactor{}, false, err
+	}
+	if tf.EnabledAt == nil {
+		return models.PendingTwoFactor{}, false, nil
+	}
+
+	pending, err = c.startTwoFactor(ctx, userID, refresh)
+	if err != nil {
+		return models.PendingTwoFactor{}, false, err
+	}
+	return pending, true, nil
+}
+
+// startTwoFactor stores a short-lived token standing in for a sign in that
+// still needs a second factor.
+func (c *Controller) startTwoFactor(ctx context.Context, userID string, refresh bool) (models.PendingTwoFactor, error) {
+	token, err := secure.Token(32)
+	if err != nil {
+		return models.PendingTwoFactor{}, err
+	}
+
+	value, err := json.Marshal(pendingSignin{UserID: userID, RefreshToken: refresh})
+	if err != nil {
+		return models.PendingTwoFactor{}, err
+	}
+
+	ttl := config.Duration("TWO_FACTOR_PENDING_TTL", time.Minute*5)
+	if err := c.cache.Set(ctx, pendingKey(secure.Hash(token)), value, ttl).Err(); err != nil {
+		return models.PendingTwoFactor{}, err
+	}
+
+	return models.PendingTwoFactor{
+		TwoFactorRequired: true,
+		PendingToken:      token,
+		ExpiresAt:         time.Now().Add(ttl),
+	}, nil
+}
+
+// verifyCode accepts either the current TOTP code or an unused recovery
+// code. Each code is only accepted once.
+func (c *Controller) verifyCode(ctx context.Context, tf models.TwoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok := totp.Validate(tf.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		n, err := c.twoFactorRepo.UseStep(ctx, tf.UserID, step)
+		return n == 1, err
+	}
+
+	n, err := c.twoFactorRepo.UseRecoveryCode(ctx, tf.UserID, secure.Hash(normalizeRecoveryCode(code)))
+	return n == 1, err
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = secure.Hash(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// @Summary		complete sign in
+// @Description	finish a sign in that requires two-factor authentication with a TOTP or recovery code
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.TwoFactorSigninBody	true	"body"
+// @Success		200		{object}	response.UserWithAuth
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		429		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/signin/2fa [post]
+func (c *Controller) SigninTwoFactor(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "Sign in two factor")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.TwoFactorSigninBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err := Validate.Struct(body); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	hash := secure.Hash(body.PendingToken)
+	value, err := c.cache.Get(spanctx, pendingKey(hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			response.Error(w, "Sign in expired, please sign in again", http.StatusUnauthorized, c.log)
+			return
+		}
+		c.log.Error("could not load pending sign in", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	var pending pendingSignin
+	if err := json.Unmarshal([]byte(value), &pending); err != nil {
+		c.log.Error("could not decode pending sign in", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	pipe := c.cache.TxPipeline()
+	attempts := pipe.Incr(spanctx, pendingAttemptsKey(hash))
+	pipe.ExpireNX(spanctx, pendingAttemptsKey(hash), config.Duration("TWO_FACTOR_PENDING_TTL", time.Minute*5))
+	if _, err := pipe.Exec(spanctx); err != nil {
+		c.log.Error("could not count two factor attempts", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if attempts.Val() > maxTwoFactorAttempts {
+		c.cache.Del(spanctx, pendingKey(hash))
+		response.Error(w, "Too many attempts, please sign in again", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	key := c.limiter.TwoFactor(pending.UserID)
+	if c.twoFactorLocked(spanctx, w, key) {
+		return
+	}
+
+	tf, err := c.twoFactorRepo.Get(spanctx, pending.UserID)
+	if err != nil {
+		c.log.Error("could not get two factor settings", zap.String("userid", pending.UserID), zap.Error(err))
+		response.Error(w, "Login Failed, please try again", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	ok, err := c.verifyCode(spanctx, tf, body.Code)
+	if err != nil {
+		c.log.Error("could not verify two factor code", zap.String("userid", pending.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if !ok {
+		if err := c.limiter.Fail(spanctx, key); err != nil {
+			c.log.Error("could not record invalid two factor code", zap.String("userid", pending.UserID), zap.Error(err))
+		}
+		response.Error(w, "Invalid two-factor code", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	if err := c.limiter.Reset(spanctx, key); err != nil {
+		c.log.Error("could not reset invalid two factor codes", zap.String("userid", pending.UserID), zap.Error(err))
+	}
+
+	// a pending sign in can only be completed once
+	deleted, err := c.cache.Del(spanctx, pendingKey(hash), pendingAttemptsKey(hash)).Result()
+	if err != nil || deleted == 0 {
+		response.Error(w, "Sign in expired, please sign in again", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	user, err := c.userRepo.GetUserByID(spanctx, pending.UserID)
+	if err != nil {
+		c.log.Error("could not get user", zap.String("userid", pending.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	c.signin(spanctx, w, r, user, pending.RefreshToken)
+}
+
+// @Summary		start two-factor enrollment
+// @Description	generate a TOTP secret to add to an authenticator app. Two-factor is not enabled until a code is confirmed.
+// @Security		BearerAuth
+// @Tags			account
+// @Produce		json
+// @Success		200		{object}	response.TwoFactorSetup
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		409		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/2fa/enroll [post]
+func (c *Controller) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "enroll two factor")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage two-factor authentication", http.StatusForbidden, c.log)
+		return
+	}
+
+	user, err := c.userRepo.GetUserByID(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not get user", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.log.Error("could not generate totp secret", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	n, err := c.twoFactorRepo.Enroll(spanctx, user.ID, secret)
+	if err != nil {
+		c.log.Error("could not enroll two factor", zap.String("userid", user.ID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if n == 0 {
+		response.Error(w, "Two-factor authentication is already enabled", http.StatusConflict, c.log)
+		return
+	}
+
+	data := models.TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(config.String("TOTP_ISSUER", "ogugu"), user.Email, secret),
+	}
+	response.Success(w, "Add the secret to your authenticator app", http.StatusOK, data, c.log)
+}
+
+// @Summary		enable two-factor
+// @Description	confirm enrollment with a code from the authenticator app and receive recovery codes
+// @Security		BearerAuth
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.TwoFactorCodeBody	true	"body"
+// @Success		200		{object}	response.RecoveryCodes
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		409		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/2fa/enable [post]
+func (c *Controller) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "enable two factor")
+	defer span.End()
+
+	sess, body, ok := c.twoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tf, err := c.twoFactorRepo.Get(spanctx, sess.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "Start two-factor enrollment first", http.StatusBadRequest, c.log)
+			return
+		}
+		c.log.Error("could not get two factor settings", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if tf.EnabledAt != nil {
+		response.Error(w, "Two-factor authentication is already enabled", http.StatusConflict, c.log)
+		return
+	}
+
+	step, valid := totp.Validate(tf.Secret, strings.TrimSpace(body.Code), time.Now())
+	if !valid {
+		response.Error(w, "Invalid two-factor code", http.StatusBadRequest, c.log)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.log.Error("could not generate recovery codes", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	n, err := c.twoFactorRepo.Enable(spanctx, sess.UserID, step, hashes)
+	if err != nil {
+		c.log.Error("could not enable two factor", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if n == 0 {
+		response.Error(w, "Two-factor authentication is already enabled", http.StatusConflict, c.log)
+		return
+	}
+
+	data := models.RecoveryCodes{Codes: codes}
+	response.Success(w, "Two-factor authentication enabled, store your recovery codes safely", http.StatusOK, data, c.log)
+}
+
+// @Summary		disable two-factor
+// @Description	turn two-factor authentication off with a TOTP or recovery code
+// @Security		BearerAuth
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body	models.TwoFactorCodeBody	true	"body"
+// @Success		204
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		429		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/2fa/disable [post]
+func (c *Controller) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "disable two factor")
+	defer span.End()
+
+	sess, body, ok := c.twoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !c.checkCode(spanctx, w, sess.UserID, body.Code) {
+		return
+	}
+
+	if _, err := c.twoFactorRepo.Delete(spanctx, sess.UserID); err != nil {
+		c.log.Error("could not disable two factor", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary		regenerate recovery codes
+// @Description	replace every recovery code with a new set
+// @Security		BearerAuth
+// @Tags			account
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.TwoFactorCodeBody	true	"body"
+// @Success		200		{object}	response.RecoveryCodes
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		403		{object}	response.Response
+// @Failure		429		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/2fa/recovery-codes [post]
+func (c *Controller) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "regenerate recovery codes")
+	defer span.End()
+
+	sess, body, ok := c.twoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !c.checkCode(spanctx, w, sess.UserID, body.Code) {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.log.Error("could not generate recovery codes", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	if err := c.twoFactorRepo.ReplaceRecoveryCodes(spanctx, sess.UserID, hashes); err != nil {
+		c.log.Error("could not replace recovery codes", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	data := models.RecoveryCodes{Codes: codes}
+	response.Success(w, "Recovery codes regenerated", http.StatusOK, data, c.log)
+}
+
+// twoFactorRequest reads the session and code body shared by the two-factor
+// management endpoints, writing an error response when either is unusable.
+func (c *Controller) twoFactorRequest(w http.ResponseWriter, r *http.Request) (models.Session, models.TwoFactorCodeBody, bool) {
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	if sess.APIKeyID != "" {
+		response.Error(w, "API keys cannot manage two-factor authentication", http.StatusForbidden, c.log)
+		return models.Session{}, models.TwoFactorCodeBody{}, false
+	}
+
+	if r.Body == nil {
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return models.Session{}, models.TwoFactorCodeBody{}, false
+	}
+
+	var body models.TwoFactorCodeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return models.Session{}, models.TwoFactorCodeBody{}, false
+	}
+
+	if err := Validate.Struct(body); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return models.Session{}, models.TwoFactorCodeBody{}, false
+	}
+
+	return sess, body, true
+}
+
+// checkCode verifies a code for a user with two-factor enabled, writing an
+// error response when it is rejected. Wrong codes count towards a per-user
+// lockout so a stolen session cannot guess its way past the second factor.
+func (c *Controller) checkCode(ctx context.Context, w http.ResponseWriter, userID, code string) bool {
+	key := c.limiter.TwoFactor(userID)
+	if c.twoFactorLocked(ctx, w, key) {
+		return false
+	}
+
+	tf, err := c.twoFactorRepo.Get(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.log.Error("could not get two factor settings", zap.String("userid", userID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return false
+	}
+	if err != nil || tf.EnabledAt == nil {
+		response.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest, c.log)
+		return false
+	}
+
+	ok, err := c.verifyCode(ctx, tf, code)
+	if err != nil {
+		c.log.Error("could not verify two factor code", zap.String("userid", userID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return false
+	}
+	if !ok {
+		if err := c.limiter.Fail(ctx, key); err != nil {
+			c.log.Error("could not record invalid two factor code", zap.String("userid", userID), zap.Error(err))
+		}
+		response.Error(w, "Invalid two-factor code", http.StatusBadRequest, c.log)
+		return false
+	}
+
+	if err := c.limiter.Reset(ctx, key); err != nil {
+		c.log.Error("could not reset invalid two factor codes", zap.String("userid", userID), zap.Error(err))
+	}
+	return true
+}
+
+// twoFactorLocked writes an error response when key entered too many wrong
+// codes. The lockout is per user, so starting a new sign in does not give
+// more guesses.
+func (c *Controller) twoFactorLocked(ctx context.Context, w http.ResponseWriter, key lockout.Key) bool {
+	retry, err := c.limiter.Locked(ctx, key)
+	if err != nil {
+		c.log.Error("could not check two factor lockout", zap.String("key", key.Name), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return true
+	}
+	if retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		response.Error(w, "Too many invalid two-factor codes, try again later", http.StatusTooManyRequests, c.log)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"ogugu/internal/database/cache/cachetest"
+	"ogugu/internal/lockout"
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/twofactor"
+	"ogugu/internal/repository/users"
+	"ogugu/internal/totp"
+)
+
+func TestSigninTwoFactorLockout(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+	rds, teardownCache := cachetest.SetupTestCache(t)
+	t.Cleanup(teardownCache)
+
+	userid := "userid"
+	_, err := users.New(db).CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	tfr := twofactor.New(db)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	_, err = tfr.Enroll(context.Background(), userid, secret)
+	require.NoError(t, err)
+	_, err = tfr.Enable(context.Background(), userid, 0, nil)
+	require.NoError(t, err)
+
+	limits := lockout.Config{MaxAttempts: 5, Window: time.Minute, Lockout: time.Minute, MaxLockout: time.Hour}
+	c := New(nil, rds, zap.NewNop(), nil, nil, nil, nil, tfr, lockout.New(rds, limits, limits))
+
+	signin := func(token, code string) *httptest.ResponseRecorder {
+		body := `{"pending_token":"` + token + `","code":"` + code + `"}`
+		w := httptest.NewRecorder()
+		c.SigninTwoFactor(w, httptest.NewRequest(http.MethodPost, "/signin/2fa", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("new pending sign ins do not reset the lockout", func(t *testing.T) {
+		// each pending token allows a few guesses, but they all count
+		// against the same user
+		var token string
+		for i := range 5 {
+			if i%2 == 0 {
+				pending, err := c.startTwoFactor(context.Background(), userid, false)
+				require.NoError(t, err)
+				token = pending.PendingToken
+			}
+			require.Equal(t, http.StatusUnauthorized, signin(token, "wrong-code").Code)
+		}
+
+		w := signin(token, "wrong-code")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("locked out users cannot try a fresh pending sign in", func(t *testing.T) {
+		pending, err := c.startTwoFactor(context.Background(), userid, false)
+		require.NoError(t, err)
+		w := signin(pending.PendingToken, "wrong-code")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
@@ -0,0 +1,17 @@
+package request
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the address of the client that made r. Proxy headers are
+// only honoured when the router is configured to trust them, in which case
+// RemoteAddr has already been rewritten.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,28 @@
+package request
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Run("strips the port", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.7:51234"
+		require.Equal(t, "203.0.113.7", ClientIP(r))
+	})
+
+	t.Run("handles ipv6", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "[2001:db8::1]:443"
+		require.Equal(t, "2001:db8::1", ClientIP(r))
+	})
+
+	t.Run("falls back to the raw address", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.7"
+		require.Equal(t, "203.0.113.7", ClientIP(r))
+	})
+}
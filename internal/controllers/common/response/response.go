@@ -70,12 +70,82 @@ type RssFeeds struct {
 	Data    []models.RssFeed
 }
 
+type DirectoryFeeds struct {
+	Message string
+	Data    []models.DirectoryFeed
+}
+
+type FeedRetention struct {
+	Message string
+	Data    models.FeedRetention
+}
+
 type Subscription struct {
 	Message string
 	Data    models.Subscription
 }
 
+type PostState struct {
+	Message string
+	Data    models.PostState
+}
+
+type FilterRule struct {
+	Message string
+	Data    models.FilterRule
+}
+
+type FilterRules struct {
+	Message string
+	Data    []models.FilterRule
+}
+
+type DigestSettings struct {
+	Message string
+	Data    models.DigestSettings
+}
+
+type DigestPreview struct {
+	Message string
+	Data    models.DigestPreview
+}
+
 type FeedPosts struct {
 	Message string
 	Data    []models.Post
 }
+
+type Sessions struct {
+	Message string
+	Data    []models.Session
+}
+
+type TokenPair struct {
+	Message string
+	Data    models.TokenPair
+}
+
+type APIKeys struct {
+	Message string
+	Data    []models.APIKey
+}
+
+type APIKeyWithSecret struct {
+	Message string
+	Data    models.APIKeyWithSecret
+}
+
+type PendingTwoFactor struct {
+	Message string
+	Data    models.PendingTwoFactor
+}
+
+type TwoFactorSetup struct {
+	Message string
+	Data    models.TwoFactorSetup
+}
+
+type RecoveryCodes struct {
+	Message string
+	Data    models.RecoveryCodes
+}
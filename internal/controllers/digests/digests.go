@@ -0,0 +1,200 @@
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/digest"
+	"ogugu/internal/models"
+	"ogugu/internal/repository/digests"
+	"ogugu/internal/repository/users"
+	"ogugu/internal/secure"
+)
+
+var (
+	tracer   = otel.Tracer("digests controller")
+	Validate = validator.New()
+)
+
+type Controller struct {
+	log        *zap.Logger
+	digestRepo *digests.Repository
+	userRepo   *users.Repository
+	builder    *digest.Builder
+}
+
+func New(l *zap.Logger, d *digests.Repository, u *users.Repository, b *digest.Builder) *Controller {
+	return &Controller{
+		log:        l,
+		digestRepo: d,
+		userRepo:   u,
+		builder:    b,
+	}
+}
+
+// settings returns the digest settings of a user, or the defaults when the
+// user never saved any.
+func (c *Controller) settings(ctx context.Context, user_id string) (models.DigestSettings, error) {
+	s, err := c.digestRepo.Get(ctx, user_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return digest.Defaults(user_id), nil
+	}
+	return s, err
+}
+
+// @Summary		get digest settings
+// @Description	get when the current user is emailed a digest of their unread posts
+// @Security		BearerAuth
+// @Tags			digest
+// @Produce		json
+// @Success		200		{object}	response.DigestSettings
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/me/digest [get]
+func (c *Controller) Get(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "get digest settings")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	s, err := c.settings(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not get digest settings", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Resource found", http.StatusOK, s, c.log)
+}
+
+// @Summary		update digest settings
+// @Description	turn the email digest off or send it daily or weekly at an hour of the user's timezone
+// @Security		BearerAuth
+// @Tags			digest
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.UpdateDigestBody	true	"digest schedule"
+// @Success		200		{object}	response.DigestSettings
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/me/digest [put]
+func (c *Controller) Update(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "update digest settings")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, "Request body cannot be empty", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.UpdateDigestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, "Request body is not valid json", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err := Validate.Struct(body); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	token, err := secure.Token(32)
+	if err != nil {
+		c.log.Error("could not generate unsubscribe token", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	s, err := c.digestRepo.Upsert(spanctx, sess.UserID, token, body)
+	if err != nil {
+		c.log.Error("could not update digest settings", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Digest settings updated", http.StatusOK, s, c.log)
+}
+
+// @Summary		preview the digest
+// @Description	render the digest the current user would be sent now, without sending it
+// @Security		BearerAuth
+// @Tags			digest
+// @Produce		json
+// @Success		200		{object}	response.DigestPreview
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/me/digest/preview [get]
+func (c *Controller) Preview(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "preview digest")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	s, err := c.settings(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not get digest settings", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	user, err := c.userRepo.GetUserByID(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not get user", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	msg, n, err := c.builder.Build(spanctx, user, s, time.Now())
+	if err != nil {
+		c.log.Error("could not build digest", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	data := models.DigestPreview{Subject: msg.Subject, Posts: n, Text: msg.Text, HTML: msg.HTML}
+	response.Success(w, "Digest preview", http.StatusOK, data, c.log)
+}
+
+// @Summary		unsubscribe from the digest
+// @Description	turn off the digest of the user the token from a digest email belongs to
+// @Tags			digest
+// @Produce		json
+// @Param			token	query		string	true	"unsubscribe token"
+// @Success		200		{object}	response.Response
+// @Failure		400		{object}	response.Response
+// @Failure		404		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/digest/unsubscribe [get]
+// @Router			/digest/unsubscribe [post]
+func (c *Controller) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "unsubscribe from digest")
+	defer span.End()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, "token is required", http.StatusBadRequest, c.log)
+		return
+	}
+
+	n, err := c.digestRepo.Unsubscribe(spanctx, token)
+	if err != nil {
+		c.log.Error("could not unsubscribe from digest", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if n == 0 {
+		response.Error(w, "unsubscribe link is invalid", http.StatusNotFound, c.log)
+		return
+	}
+
+	response.Success(w, "You will no longer receive digests", http.StatusOK, nil, c.log)
+}
@@ -0,0 +1,153 @@
+package filterrules
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/filters"
+	"ogugu/internal/models"
+	"ogugu/internal/repository/filterrules"
+)
+
+var (
+	tracer   = otel.Tracer("filter rules controller")
+	Validate = validator.New()
+)
+
+// maxRules caps the rules of a user, as every rule is evaluated for every
+// post of their timeline.
+const maxRules = 100
+
+type Controller struct {
+	log      *zap.Logger
+	ruleRepo *filterrules.Repository
+}
+
+func New(l *zap.Logger, f *filterrules.Repository) *Controller {
+	return &Controller{
+		log:      l,
+		ruleRepo: f,
+	}
+}
+
+// @Summary		create a filter rule
+// @Description	hide, mark read or star posts whose title, description, author, category or feed matches a keyword or regex.
+// @Security		BearerAuth
+// @Tags			filters
+// @Accept			json
+// @Produce		json
+// @Param			body	body		models.CreateFilterRuleBody	true	"rule"
+// @Success		201		{object}	response.FilterRule
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/filters [post]
+func (c *Controller) Create(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "create filter rule")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, "Request body cannot be empty", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.CreateFilterRuleBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, "Request body is not valid json", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err := Validate.Struct(body); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	rule := models.FilterRule{Field: body.Field, Match: body.Match, Pattern: body.Pattern, Action: body.Action}
+	if _, err := filters.Compile([]models.FilterRule{rule}); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	rules, err := c.ruleRepo.ListByUser(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not list filter rules", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	if len(rules) >= maxRules {
+		response.Error(w, "too many filter rules", http.StatusBadRequest, c.log)
+		return
+	}
+
+	rule, err = c.ruleRepo.Create(spanctx, ulid.Make().String(), sess.UserID, body)
+	if err != nil {
+		c.log.Error("could not create filter rule", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Filter rule created", http.StatusCreated, rule, c.log)
+}
+
+// @Summary		list filter rules
+// @Description	list the filter rules of the current user
+// @Security		BearerAuth
+// @Tags			filters
+// @Produce		json
+// @Success		200		{object}	response.FilterRules
+// @Failure		401		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/filters [get]
+func (c *Controller) List(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "list filter rules")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	rules, err := c.ruleRepo.ListByUser(spanctx, sess.UserID)
+	if err != nil {
+		c.log.Error("could not list filter rules", zap.String("userid", sess.UserID), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Resources found", http.StatusOK, rules, c.log)
+}
+
+// @Summary		delete a filter rule
+// @Description	delete a filter rule belonging to the current user
+// @Security		BearerAuth
+// @Tags			filters
+// @Produce		json
+// @Param			id		path	string	true	"Filter rule ID"
+// @Success		204
+// @Failure		401		{object}	response.Response
+// @Failure		404		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/filters/{id} [delete]
+func (c *Controller) Delete(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "delete filter rule")
+	defer span.End()
+
+	sess := r.Context().Value(models.AuthSessionKey).(models.Session)
+	id := r.PathValue("id")
+	n, err := c.ruleRepo.Delete(spanctx, sess.UserID, id)
+	if err != nil {
+		c.log.Error("could not delete filter rule", zap.String("id", id), zap.Error(err))
+		response.Error(w, "An error occured while deleting the filter rule", http.StatusInternalServerError, c.log)
+		return
+	}
+	if n == 0 {
+		response.Error(w, "filter rule with id not found", http.StatusNotFound, c.log)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
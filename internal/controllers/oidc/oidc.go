@@ -0,0 +1,304 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"ogugu/internal/controllers/common/request"
+	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/models"
+	provider "ogugu/internal/oidc"
+	"ogugu/internal/repository/identities"
+	"ogugu/internal/repository/users"
+	"ogugu/internal/secure"
+	"ogugu/internal/session"
+)
+
+var tracer = otel.Tracer("OIDC Controller")
+
+// stateTTL is how long a user has to complete sign in at the provider.
+const stateTTL = time.Minute * 10
+
+var errEmailTaken = errors.New("email belongs to an account that is not linked")
+
+// TwoFactor holds back sign ins of users with two-factor enabled until they
+// enter a code, the same as a password sign in.
+type TwoFactor interface {
+	StartTwoFactor(ctx context.Context, userID string, refresh bool) (models.PendingTwoFactor, bool, error)
+}
+
+type Controller struct {
+	provider     *provider.Provider
+	issuer       string
+	cache        *redis.Client
+	sessions     session.Backend
+	log          *zap.Logger
+	userRepo     *users.Repository
+	identityRepo *identities.Repository
+	twoFactor    TwoFactor
+}
+
+func New(
+	p *provider.Provider,
+	issuer string,
+	cache *redis.Client,
+	s session.Backend,
+	l *zap.Logger,
+	u *users.Repository,
+	i *identities.Repository,
+	tf TwoFactor,
+) *Controller {
+	return &Controller{
+		provider:     p,
+		issuer:       issuer,
+		cache:        cache,
+		sessions:     s,
+		log:          l,
+		userRepo:     u,
+		identityRepo: i,
+		twoFactor:    tf,
+	}
+}
+
+// pending is kept between the redirect to the provider and the callback.
+type pending struct {
+	Verifier     string `json:"verifier"`
+	Nonce        string `json:"nonce"`
+	RefreshToken bool   `json:"refresh_token"`
+}
+
+func stateKey(state string) string {
+	return "oidc:state:" + state
+}
+
+// @Summary		sign in with the identity provider
+// @Description	redirect to the configured OpenID Connect provider to sign in
+// @Tags			account
+// @Param			refresh_token	query	bool	false	"issue a refresh token on sign in"
+// @Success		302
+// @Failure		500		{object}	response.Response
+// @Failure		502		{object}	response.Response
+// @Router			/oidc/login [get]
+func (c *Controller) Login(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "oidc login")
+	defer span.End()
+
+	state, err := secure.Token(16)
+	if err != nil {
+		c.log.Error("could not generate oidc state", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	nonce, err := secure.Token(16)
+	if err != nil {
+		c.log.Error("could not generate oidc nonce", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	verifier, err := provider.NewVerifier()
+	if err != nil {
+		c.log.Error("could not generate pkce verifier", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	authURL, err := c.provider.AuthCodeURL(spanctx, state, nonce, provider.Challenge(verifier))
+	if err != nil {
+		c.log.Error("could not build authorization url", zap.Error(err))
+		response.Error(w, "The identity provider is unavailable", http.StatusBadGateway, c.log)
+		return
+	}
+
+	value, err := json.Marshal(pending{
+		Verifier:     verifier,
+		Nonce:        nonce,
+		RefreshToken: r.URL.Query().Get("refresh_token") == "true",
+	})
+	if err != nil {
+		c.log.Error("could not encode oidc state", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	if err := c.cache.Set(spanctx, stateKey(state), value, stateTTL).Err(); err != nil {
+		c.log.Error("could not store oidc state", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// @Summary		identity provider callback
+// @Description	complete sign in with the identity provider, creating an account on first sign in. Users with two-factor enabled get a pending token to finish at /signin/2fa.
+// @Tags			account
+// @Produce		json
+// @Param			code	query		string	true	"authorization code"
+// @Param			state	query		string	true	"state"
+// @Success		200		{object}	response.UserWithAuth
+// @Success		202		{object}	response.PendingTwoFactor
+// @Failure		400		{object}	response.Response
+// @Failure		401		{object}	response.Response
+// @Failure		409		{object}	response.Response
+// @Failure		500		{object}	response.Response
+// @Router			/oidc/callback [get]
+func (c *Controller) Callback(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "oidc callback")
+	defer span.End()
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		c.log.Warn("identity provider returned an error", zap.String("error", e), zap.String("description", q.Get("error_description")))
+		response.Error(w, "Sign in was not completed", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	code, state := q.Get("code"), q.Get("state")
+	if code == "" || state == "" {
+		response.Error(w, "code and state are required", http.StatusBadRequest, c.log)
+		return
+	}
+
+	// GETDEL makes each state single use
+	value, err := c.cache.GetDel(spanctx, stateKey(state)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			response.Error(w, "Sign in expired, please try again", http.StatusBadRequest, c.log)
+			return
+		}
+		c.log.Error("could not load oidc state", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	var p pending
+	if err := json.Unmarshal([]byte(value), &p); err != nil {
+		c.log.Error("could not decode oidc state", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	claims, err := c.provider.Exchange(spanctx, code, p.Verifier, p.Nonce)
+	if err != nil {
+		c.log.Warn("could not exchange authorization code", zap.Error(err))
+		response.Error(w, "Sign in failed", http.StatusUnauthorized, c.log)
+		return
+	}
+
+	user, err := c.resolveUser(spanctx, claims)
+	if err != nil {
+		if errors.Is(err, errEmailTaken) {
+			response.Error(w, "An account with this email already exists", http.StatusConflict, c.log)
+			return
+		}
+		c.log.Error("could not resolve oidc user", zap.String("subject", claims.Subject), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	pending, required, err := c.twoFactor.StartTwoFactor(spanctx, user.ID, p.RefreshToken)
+	if err != nil {
+		c.log.Error("could not start two factor sign in", zap.String("userid", user.ID), zap.Error(err))
+		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
+		return
+	}
+	if required {
+		response.Success(w, "Two-factor authentication required", http.StatusAccepted, pending, c.log)
+		return
+	}
+
+	meta := models.Session{
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IP:        request.ClientIP(r),
+	}
+
+	var data models.UserWithAuth
+	var sess models.Session
+	if p.RefreshToken {
+		data.AuthToken, data.RefreshToken, sess, err = c.sessions.CreateWithRefresh(spanctx, meta)
+	} else {
+		data.AuthToken, sess, err = c.sessions.Create(spanctx, meta)
+	}
+	if err != nil {
+		c.log.Error("unable to create session", zap.Error(err))
+		response.Error(w, "Login Failed, please try again", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	data.User = user
+	data.ExpiresAt = sess.ExpiryTime
+	response.Success(w, "Login Successful", http.StatusOK, data, c.log)
+}
+
+// resolveUser returns the user linked to the identity in claims. An
+// unlinked identity is linked to the account with the same email when both
+// sides have verified it, otherwise a new account is created.
+func (c *Controller) resolveUser(ctx context.Context, claims provider.Claims) (models.User, error) {
+	userID, err := c.identityRepo.GetUserID(ctx, c.issuer, claims.Subject)
+	if err == nil {
+		return c.userRepo.GetUserByID(ctx, userID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, err
+	}
+
+	if claims.Email == "" {
+		return models.User{}, errors.New("identity provider did not return an email address")
+	}
+
+	user, err := c.userRepo.GetUser(ctx, "email", claims.Email)
+	switch {
+	case err == nil:
+		// linking on an unverified email would let whoever registered it
+		// first take over the other account
+		if !claims.EmailVerified || user.EmailVerifiedAt == nil {
+			return models.User{}, errEmailTaken
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = c.userRepo.CreateUser(ctx, ulid.Make().String(), models.CreateUserBody{
+			Username: username(claims),
+			Email:    claims.Email,
+			Avatar:   claims.Picture,
+		})
+		if err != nil {
+			return models.User{}, err
+		}
+		if claims.EmailVerified {
+			user, err = c.userRepo.MarkEmailVerified(ctx, user.ID)
+			if err != nil {
+				return models.User{}, err
+			}
+		}
+	default:
+		return models.User{}, err
+	}
+
+	err = c.identityRepo.Create(ctx, ulid.Make().String(), user.ID, c.issuer, claims.Subject, claims.Email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func username(claims provider.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if claims.Name != "" {
+		return claims.Name
+	}
+	name, _, _ := strings.Cut(claims.Email, "@")
+	return name
+}
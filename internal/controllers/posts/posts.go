@@ -2,17 +2,23 @@ package posts
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
 	"go.opentelemetry.io/otel"
 	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/models"
 	"ogugu/internal/repository/posts"
 )
 
-var tracer = otel.Tracer("posts controller")
+var (
+	tracer   = otel.Tracer("posts controller")
+	Validate = validator.New()
+)
 
 type Controller struct {
 	log      *zap.Logger
@@ -30,14 +36,18 @@ func New(log *zap.Logger, ps *posts.Repository) *Controller {
 // @Description	get all posts
 // @Tags			posts
 // @Produce		json
-// @Success		200		{object}	response.Posts		"Posts found"
-// @Failure		default	{object}	response.Response	"Unable to get posts"
+// @Param			author		query		string				false	"only posts by this author"
+// @Param			category	query		string				false	"only posts in this category"
+// @Success		200			{object}	response.Posts		"Posts found"
+// @Failure		default		{object}	response.Response	"Unable to get posts"
 // @Router			/posts [get]
 func (c *Controller) FetchPosts(w http.ResponseWriter, r *http.Request) {
 	spanctx, span := tracer.Start(r.Context(), "fetch all posts")
 	defer span.End()
 
-	feed, err := c.postRepo.Fetch(spanctx)
+	q := r.URL.Query()
+	filter := models.PostFilter{Author: q.Get("author"), Category: q.Get("category")}
+	feed, err := c.postRepo.Fetch(spanctx, filter)
 	if err != nil {
 		c.log.Error("An error occured while fetching all post entries", zap.Error(err))
 		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
@@ -79,3 +89,64 @@ func (c *Controller) GetPostByID(w http.ResponseWriter, r *http.Request) {
 
 	response.Success(w, "resource with id found", http.StatusOK, post, c.log)
 }
+
+// @Summary		Mark a post read or starred
+// @Description	Set the signed in user's read and star marks on a post. Omitted marks are left unchanged.
+// @Tags			posts
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string						true	"Post ID"
+// @Param			body	body		models.UpdatePostStateBody	true	"Read and star marks"
+// @Success		200		{object}	response.PostState			"Post state updated"
+// @Failure		400		{object}	response.Response			"Invalid or malformed request body"
+// @Failure		401		{object}	response.Response			"Unauthorized"
+// @Failure		404		{object}	response.Response			"Post with ID not found"
+// @Failure		500		{object}	response.Response			"An error occured on the server"
+// @Failure		default	{object}	response.Response			"An error occured"
+// @Router			/posts/{id}/state [put]
+func (c *Controller) SetState(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "set post state")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.UpdatePostStateBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("request body failed some validations", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, err := c.postRepo.GetByID(spanctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "post with id not found", http.StatusNotFound, c.log)
+			return
+		}
+		c.log.Error("unable to get post", zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	session := r.Context().Value(models.AuthSessionKey).(models.Session)
+	state, err := c.postRepo.SetState(spanctx, session.UserID, id, body.Read, body.Starred)
+	if err != nil {
+		c.log.Error("unable to set post state", zap.Error(err), zap.String("id", id))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "post state updated", http.StatusOK, state, c.log)
+}
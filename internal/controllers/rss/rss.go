@@ -1,12 +1,14 @@
 package rss
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
-	"encoding/xml"
 	"errors"
-	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -15,8 +17,10 @@ import (
 	"go.uber.org/zap"
 
 	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/fetcher"
 	"ogugu/internal/models"
 	"ogugu/internal/repository/rss"
+	"ogugu/internal/urlnorm"
 )
 
 var (
@@ -27,30 +31,47 @@ var (
 type Controller struct {
 	log     *zap.Logger
 	rssRepo *rss.Repository
+	fetcher fetcher.Fetcher
 }
 
-func New(l *zap.Logger, r *rss.Repository) *Controller {
+func New(l *zap.Logger, r *rss.Repository, f fetcher.Fetcher) *Controller {
 	return &Controller{
 		log:     l,
 		rssRepo: r,
+		fetcher: f,
 	}
 }
 
-// @Summary		Find all RSS feeds
-// @Description	Retrieve all RSS Feeds in the database.
+// @Summary		Browse the feed directory
+// @Description	Search feeds and sort them by popularity, recent activity or title. Every feed carries its subscriber count and posting frequency.
 // @Tags			rss
 // @Produce		json
-// @Success		200		{object}	response.RssFeeds	"RSS Feeds found"
-// @Failure		400		{object}	response.Response	"Invalid request"
-// @Failure		404		{object}	response.Response	"RSS Feed not found"
-// @Failure		500		{object}	response.Response	"An error occured on the server"
-// @Failure		default	{object}	response.Response	"An error occured"
+// @Param			q			query		string					false	"text to find in the title, description or links"
+// @Param			language	query		string					false	"only feeds in this language, e.g. en or en-us"
+// @Param			category	query		string					false	"only feeds with posts in this category"
+// @Param			sort		query		string					false	"sort order"	Enums(subscribers, recent, title)
+// @Success		200			{object}	response.DirectoryFeeds	"RSS Feeds found"
+// @Failure		400			{object}	response.Response		"Invalid request"
+// @Failure		500			{object}	response.Response		"An error occured on the server"
+// @Failure		default		{object}	response.Response		"An error occured"
 // @Router			/feed [get]
 func (c *Controller) Fetch(w http.ResponseWriter, r *http.Request) {
 	spanctx, span := tracer.Start(r.Context(), "fetch all rss")
 	defer span.End()
 
-	feed, err := c.rssRepo.Fetch(spanctx)
+	q := r.URL.Query()
+	query := models.FeedQuery{
+		Search:   q.Get("q"),
+		Language: q.Get("language"),
+		Category: q.Get("category"),
+		Sort:     q.Get("sort"),
+	}
+	if err := Validate.Struct(query); err != nil {
+		response.Error(w, "sort must be one of subscribers, recent or title", http.StatusBadRequest, c.log)
+		return
+	}
+
+	feed, err := c.rssRepo.Directory(spanctx, query)
 	if err != nil {
 		c.log.Error("An error occured while fetching all rss entries", zap.Error(err))
 		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
@@ -138,11 +159,12 @@ func (c *Controller) FindRssByID(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary		Create a new RSS feed
-// @Description	Create a new RSS feed by providing the feed's name and link.
+// @Description	Create a new RSS feed by providing the feed's name and link. Links to a feed that exists, after following redirects and ignoring the scheme, "www." and trailing slashes, return that feed.
 // @Tags			rss
 // @Accept			json
 // @Produce		json
 // @Param			body	body		models.CreateRssBody	true	"Create a new RSS feed"
+// @Success		200		{object}	response.RssFeed		"RSS Feed exists"
 // @Success		201		{object}	response.RssFeed		"RSS Feed created"
 // @Failure		400		{object}	response.Response		"Invalid or malformed request body"
 // @Failure		500		{object}	response.Response		"An error occured on the server"
@@ -172,19 +194,35 @@ func (c *Controller) CreateRss(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	meta, err := getRSSMeta(body.Link)
+	if c.existing(spanctx, w, body.Link) {
+		return
+	}
+
+	meta, link, err := c.getRSSMeta(spanctx, body.Link)
 	if err != nil {
 		c.log.Error(err.Error(), zap.Error(err))
 		response.Error(w, "an error occured while fetching rss metadata", http.StatusUnprocessableEntity, c.log)
 		return
 	}
 
+	// the link may have redirected to a feed that exists
+	link = urlnorm.Canonical(link)
+	if c.existing(spanctx, w, link) {
+		return
+	}
+
 	if meta.Channel.Title == "" {
 		meta.Channel.Title = "Untitled Feed"
 	}
 
 	id := ulid.Make().String()
-	feed, err := c.rssRepo.Create(spanctx, id, body.Link, meta)
+	feed, err := c.rssRepo.Create(spanctx, id, link, meta)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the feed was created concurrently
+		if c.existing(spanctx, w, link) {
+			return
+		}
+	}
 	if err != nil {
 		c.log.Error("could not create new feed", zap.Error(err))
 		response.Error(w, "could not create new feed", http.StatusInternalServerError, c.log)
@@ -194,33 +232,242 @@ func (c *Controller) CreateRss(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, "rss feed created successfully", http.StatusCreated, feed, c.log)
 }
 
-func getRSSMeta(link string) (models.RSSMeta, error) {
-	res, err := http.Get(link)
+// existing responds with the feed at link, or a variant of it, and reports
+// whether there was one.
+func (c *Controller) existing(ctx context.Context, w http.ResponseWriter, link string) bool {
+	feed, err := c.rssRepo.FindByFeedURL(ctx, link)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
 	if err != nil {
-		return models.RSSMeta{}, err
+		c.log.Error("could not find feed by link", zap.String("link", link), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return true
 	}
 
-	body, err := io.ReadAll(res.Body)
-	defer res.Body.Close()
+	response.Success(w, "rss feed already exists", http.StatusOK, feed, c.log)
+	return true
+}
+
+// getRSSMeta fetches the feed at link and returns its metadata and the url
+// it was found at after following redirects.
+func (c *Controller) getRSSMeta(ctx context.Context, link string) (models.RSSMeta, string, error) {
+	res, err := c.fetcher.Fetch(ctx, link, fetcher.Validators{})
 	if err != nil {
-		return models.RSSMeta{}, err
+		return models.RSSMeta{}, "", err
 	}
 
 	var meta models.RSSMeta
-	err = xml.Unmarshal(body, &meta)
+	meta.Channel.Title = res.Feed.Title
+	meta.Channel.Description = res.Feed.Description
+	meta.Channel.Link = res.Feed.Link
+	meta.Channel.Image = res.Feed.Image
+	meta.Channel.Language = res.Feed.Language
+	meta.Channel.Generator = res.Feed.Generator
+	meta.Channel.Copyright = res.Feed.Copyright
+	meta.Channel.Podcast = (*models.Podcast)(res.Feed.Podcast)
+
+	lastModified := res.Feed.Updated
+	if t, err := http.ParseTime(res.Validators.LastModified); err == nil {
+		lastModified = t
+	}
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
+	meta.Channel.LastModified = lastModified.UTC().Format(time.RFC1123)
+
+	final := res.URL
+	if final == "" {
+		final = link
+	}
+	return meta, final, nil
+}
+
+// @Summary		Toggle full text extraction for an RSS feed
+// @Description	When enabled, the worker downloads the page of every new post and stores the extracted article. Feeds are shared by every subscriber, so only admins can change this.
+// @Tags			rss
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string						true	"ID of the RSS feed"
+// @Param			body	body		models.UpdateFullTextBody	true	"Full text setting"
+// @Success		200		{object}	response.RssFeed			"RSS Feed updated"
+// @Failure		400		{object}	response.Response			"Invalid or malformed request body"
+// @Failure		401		{object}	response.Response			"Unauthorized"
+// @Failure		403		{object}	response.Response			"Only admins can toggle full text extraction"
+// @Failure		404		{object}	response.Response			"RSS Feed not found"
+// @Failure		500		{object}	response.Response			"An error occured on the server"
+// @Failure		default	{object}	response.Response			"An error occured"
+// @Router			/feed/{id}/full-text [put]
+func (c *Controller) SetFullText(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "set rss full text")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.UpdateFullTextBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("request body failed some validations", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	id := r.PathValue("id")
+	feed, err := c.rssRepo.UpdateField(spanctx, id, "fetch_full_text", *body.FetchFullText)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.log.Warn("rss entry not found", zap.String("id", id))
+			response.Error(w, "rss with id not found", http.StatusNotFound, c.log)
+			return
+		}
+
+		c.log.Error("an error occured while updating rss entry", zap.String("id", id), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "rss feed updated successfully", http.StatusOK, feed, c.log)
+}
+
+// @Summary		Get the retention policy of an RSS feed
+// @Description	Null limits fall back to the global retention policy and zero limits keep posts forever.
+// @Tags			rss
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string						true	"ID of the RSS feed"
+// @Success		200		{object}	response.FeedRetention		"Retention policy"
+// @Failure		401		{object}	response.Response			"Unauthorized"
+// @Failure		404		{object}	response.Response			"RSS Feed not found"
+// @Failure		500		{object}	response.Response			"An error occured on the server"
+// @Router			/feed/{id}/retention [get]
+func (c *Controller) GetRetention(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "get rss retention")
+	defer span.End()
+
+	id := r.PathValue("id")
+	retention, err := c.rssRepo.GetRetention(spanctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "rss with id not found", http.StatusNotFound, c.log)
+			return
+		}
+
+		c.log.Error("an error occured while fetching rss retention", zap.String("id", id), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+
+	response.Success(w, "Resource found", http.StatusOK, retention, c.log)
+}
+
+// @Summary		Set the retention policy of an RSS feed
+// @Description	Keep posts of the feed for at most max_age_days days and at most max_items posts. Starred posts are always kept. Feeds are shared by every subscriber, so only admins can change this.
+// @Tags			rss
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string						true	"ID of the RSS feed"
+// @Param			body	body		models.UpdateRetentionBody	true	"Retention policy"
+// @Success		200		{object}	response.FeedRetention		"Retention policy updated"
+// @Failure		400		{object}	response.Response			"Invalid or malformed request body"
+// @Failure		401		{object}	response.Response			"Unauthorized"
+// @Failure		403		{object}	response.Response			"Only admins can change the retention policy"
+// @Failure		404		{object}	response.Response			"RSS Feed not found"
+// @Failure		500		{object}	response.Response			"An error occured on the server"
+// @Router			/feed/{id}/retention [put]
+func (c *Controller) SetRetention(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "set rss retention")
+	defer span.End()
+
+	if r.Body == nil {
+		c.log.Error("request body is missing")
+		response.Error(w, "Request body missing", http.StatusBadRequest, c.log)
+		return
+	}
+
+	var body models.UpdateRetentionBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		c.log.Error("invalid request body", zap.Error(err))
+		response.Error(w, "Incorrect or Malformed request body", http.StatusBadRequest, c.log)
+		return
+	}
+
+	if err = Validate.Struct(body); err != nil {
+		c.log.Error("request body failed some validations", zap.Error(err))
+		response.Error(w, err.Error(), http.StatusBadRequest, c.log)
+		return
+	}
+
+	id := r.PathValue("id")
+	retention, err := c.rssRepo.SetRetention(spanctx, id, body)
 	if err != nil {
-		return models.RSSMeta{}, err
+		if errors.Is(err, sql.ErrNoRows) {
+			c.log.Warn("rss entry not found", zap.String("id", id))
+			response.Error(w, "rss with id not found", http.StatusNotFound, c.log)
+			return
+		}
+
+		c.log.Error("an error occured while updating rss retention", zap.String("id", id), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
 	}
 
-	if err = Validate.Struct(meta); err != nil {
-		return models.RSSMeta{}, errors.New(err.Error())
+	response.Success(w, "rss retention updated successfully", http.StatusOK, retention, c.log)
+}
+
+// @Summary		Get the icon of an RSS feed
+// @Description	Serve the favicon of the feed's site, cached by the worker.
+// @Tags			rss
+// @Produce		image/png,image/gif,image/jpeg,image/webp,image/x-icon
+// @Param			id		path		string				true	"ID of the RSS feed"
+// @Success		200		{file}		binary				"Feed icon"
+// @Success		304		"Icon unchanged"
+// @Failure		404		{object}	response.Response	"RSS Feed or icon not found"
+// @Failure		500		{object}	response.Response	"An error occured on the server"
+// @Router			/feed/{id}/icon [get]
+func (c *Controller) Icon(w http.ResponseWriter, r *http.Request) {
+	spanctx, span := tracer.Start(r.Context(), "get rss icon")
+	defer span.End()
+
+	id := r.PathValue("id")
+	icon, err := c.rssRepo.GetIcon(spanctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, "icon not found", http.StatusNotFound, c.log)
+			return
+		}
+
+		c.log.Error("an error occured while fetching rss icon", zap.String("id", id), zap.Error(err))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
 	}
 
-	lastModified := res.Header.Get("Last-Modified")
-	if lastModified == "" {
-		meta.Channel.LastModified = time.Now().Format(time.RFC1123)
-	} else {
-		meta.Channel.LastModified = lastModified
+	sum := sha256.Sum256(icon.Data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	return meta, nil
+
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(icon.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.WriteHeader(http.StatusOK)
+	w.Write(icon.Data)
 }
@@ -3,6 +3,7 @@ package subscriptions
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/oklog/ulid/v2"
@@ -11,7 +12,11 @@ import (
 	"go.uber.org/zap"
 
 	"ogugu/internal/controllers/common/response"
+	"ogugu/internal/filters"
 	"ogugu/internal/models"
+	"ogugu/internal/ranking"
+	"ogugu/internal/repository/filterrules"
+	postRepo "ogugu/internal/repository/posts"
 	"ogugu/internal/repository/subscriptions"
 )
 
@@ -21,19 +26,22 @@ var (
 )
 
 type Controller struct {
-	cache   *redis.Client
-	log     *zap.Logger
-	subRepo *subscriptions.Repository
+	cache    *redis.Client
+	log      *zap.Logger
+	subRepo  *subscriptions.Repository
+	ruleRepo *filterrules.Repository
 }
 
 func New(cache *redis.Client,
 	log *zap.Logger,
 	r *subscriptions.Repository,
+	f *filterrules.Repository,
 ) *Controller {
 	return &Controller{
-		cache:   cache,
-		log:     log,
-		subRepo: r,
+		cache:    cache,
+		log:      log,
+		subRepo:  r,
+		ruleRepo: f,
 	}
 }
 
@@ -165,28 +173,61 @@ func (c *Controller) Unsubscribe(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary		get posts
-// @Description	get posts from feed that user is subscribed to
+// @Description	get posts from feed that user is subscribed to, without posts hidden by the user's filter rules.
+// @Description	A story published by several feeds appears once, listing the other feeds in also_in.
 // @Tags			subscription
 // @Security		BearerAuth
 // @Accept			json
 // @Produce		json
-// @Success		200		{object}	response.FeedPosts
-// @Failure		400		{object}	response.Response
-// @Failure		500		{object}	response.Response
-// @Failure		default	{object}	response.Response
+// @Param			author		query		string	false	"only posts by this author"
+// @Param			category	query		string	false	"only posts in this category"
+// @Param			sort		query		string	false	"ranked orders posts by recency, reading history and feed frequency"	Enums(ranked)
+// @Success		200			{object}	response.FeedPosts
+// @Failure		400			{object}	response.Response
+// @Failure		500			{object}	response.Response
+// @Failure		default		{object}	response.Response
 // @Router			/subscriptions/posts [get]
 func (c *Controller) GetPostFromSub(w http.ResponseWriter, r *http.Request) {
 	spanctx, span := tracer.Start(r.Context(), "get post from sub")
 	defer span.End()
 
 	session := r.Context().Value(models.AuthSessionKey).(models.Session)
-	posts, err := c.subRepo.GetPostFromSubScriptions(spanctx, session.UserID)
+	q := r.URL.Query()
+	filter := models.PostFilter{Author: q.Get("author"), Category: q.Get("category")}
+
+	var posts []models.Post
+	var err error
+	switch q.Get("sort") {
+	case "":
+		posts, err = c.subRepo.GetPostFromSubScriptions(spanctx, session.UserID, filter)
+	case "ranked":
+		var candidates []models.RankCandidate
+		candidates, err = c.subRepo.GetRankingCandidates(spanctx, session.UserID, filter, ranking.Candidates)
+		posts = ranking.Rank(time.Now(), candidates)
+	default:
+		response.Error(w, "sort must be ranked or empty", http.StatusBadRequest, c.log)
+		return
+	}
 	if err != nil {
 		c.log.Error("An error occured while fetching all post entries", zap.Error(err), zap.String("userid", session.UserID))
 		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
 		return
 	}
 
+	rules, err := c.ruleRepo.ListByUser(spanctx, session.UserID)
+	if err != nil {
+		c.log.Error("could not list filter rules", zap.Error(err), zap.String("userid", session.UserID))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	set, err := filters.Compile(rules)
+	if err != nil {
+		c.log.Error("could not compile filter rules", zap.Error(err), zap.String("userid", session.UserID))
+		response.Error(w, "internal server error", http.StatusInternalServerError, c.log)
+		return
+	}
+	posts = postRepo.Collapse(set.Visible(posts))
+
 	msg := "resources found"
 	if len(posts) == 0 {
 		msg = "no resource found"
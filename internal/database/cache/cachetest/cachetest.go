@@ -0,0 +1,42 @@
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"ogugu/internal/database/cache"
+)
+
+func SetupTestCache(t *testing.T) (*redis.Client, func()) {
+	containerReq := testcontainers.ContainerRequest{
+		Image:        "redis:alpine",
+		ExposedPorts: []string{"6379/tcp"},
+		WaitingFor:   wait.ForListeningPort("6379/tcp"),
+	}
+
+	redisContainer, err := testcontainers.GenericContainer(
+		context.Background(),
+		testcontainers.GenericContainerRequest{
+			ContainerRequest: containerReq,
+			Started:          true,
+		})
+	require.NoError(t, err)
+
+	port, err := redisContainer.MappedPort(context.Background(), "6379")
+	require.NoError(t, err)
+
+	client, err := cache.Setup(fmt.Sprintf("redis://localhost:%s", port.Port()))
+	require.NoError(t, err)
+
+	return client, func() {
+		client.Close()
+		err := redisContainer.Terminate(context.Background())
+		require.NoError(t, err)
+	}
+}
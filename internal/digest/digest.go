@@ -0,0 +1,182 @@
+// Package digest schedules and renders the email digest of a user's unread
+// posts.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	texttemplate "text/template"
+	"time"
+	// timezones are looked up even where the system has no zoneinfo
+	_ "time/tzdata"
+
+	"ogugu/internal/filters"
+	"ogugu/internal/mailer"
+	"ogugu/internal/models"
+	"ogugu/internal/repository/filterrules"
+	"ogugu/internal/repository/posts"
+	"ogugu/internal/repository/subscriptions"
+)
+
+// MaxPosts caps the posts of one digest.
+const MaxPosts = 50
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(
+		htmltemplate.New("digest.html").Funcs(htmltemplate.FuncMap{"date": date}).ParseFS(templates, "templates/digest.html"),
+	)
+	textTemplate = texttemplate.Must(
+		texttemplate.New("digest.txt").Funcs(texttemplate.FuncMap{"date": date}).ParseFS(templates, "templates/digest.txt"),
+	)
+)
+
+func date(t time.Time) string {
+	return t.Format("Mon, 2 Jan 2006 15:04")
+}
+
+// Defaults are the settings of a user who never changed them.
+func Defaults(user_id string) models.DigestSettings {
+	return models.DigestSettings{UserID: user_id, Frequency: "off", Hour: 8, Weekday: 1, Timezone: "UTC"}
+}
+
+// period is how far back a first digest reaches.
+func period(frequency string) time.Duration {
+	if frequency == "weekly" {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Last returns the latest time at or before now a digest was scheduled.
+func Last(s models.DigestSettings, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(loc)
+	last := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, loc)
+	step := 1
+	if s.Frequency == "weekly" {
+		step = 7
+		last = last.AddDate(0, 0, -((int(local.Weekday()) - s.Weekday + 7) % 7))
+	}
+	if last.After(local) {
+		last = last.AddDate(0, 0, -step)
+	}
+	return last, nil
+}
+
+// Due reports whether a digest was scheduled since the last one was sent,
+// or, before the first one, since the settings were saved.
+func Due(s models.DigestSettings, now time.Time) (bool, error) {
+	if s.Frequency != "daily" && s.Frequency != "weekly" {
+		return false, nil
+	}
+	last, err := Last(s, now)
+	if err != nil {
+		return false, err
+	}
+
+	ref := s.UpdatedAt
+	if s.LastSentAt != nil {
+		ref = *s.LastSentAt
+	}
+	return ref.Before(last), nil
+}
+
+// Since returns the time from which posts are included in a digest.
+func Since(s models.DigestSettings, now time.Time) time.Time {
+	if s.LastSentAt != nil {
+		return *s.LastSentAt
+	}
+	return now.Add(-period(s.Frequency))
+}
+
+// Data is what the digest templates render.
+type Data struct {
+	Subject        string
+	User           models.User
+	Posts          []models.Post
+	Frequency      string
+	Since          time.Time
+	UnsubscribeURL string
+}
+
+// Render composes the html and text email of a digest.
+func Render(d Data) (mailer.Message, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return mailer.Message{}, err
+	}
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{To: d.User.Email, Subject: d.Subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+// Builder gathers a user's unread posts into a digest.
+type Builder struct {
+	subs  *subscriptions.Repository
+	rules *filterrules.Repository
+	// apiURL is where unsubscribe links point.
+	apiURL string
+}
+
+func NewBuilder(subs *subscriptions.Repository, rules *filterrules.Repository, apiURL string) *Builder {
+	return &Builder{subs: subs, rules: rules, apiURL: apiURL}
+}
+
+// Build returns the digest of user at now and how many posts it lists.
+// Posts hidden by the user's filter rules are left out and duplicates are
+// collapsed, as in the timeline. Times are shown in the user's timezone.
+func (b *Builder) Build(
+	ctx context.Context, user models.User, s models.DigestSettings, now time.Time,
+) (mailer.Message, int, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return mailer.Message{}, 0, err
+	}
+
+	unread, err := b.subs.GetUnreadPosts(ctx, user.ID, Since(s, now), MaxPosts)
+	if err != nil {
+		return mailer.Message{}, 0, err
+	}
+	rules, err := b.rules.ListByUser(ctx, user.ID)
+	if err != nil {
+		return mailer.Message{}, 0, err
+	}
+	set, err := filters.Compile(rules)
+	if err != nil {
+		return mailer.Message{}, 0, err
+	}
+	unread = posts.Collapse(set.Visible(unread))
+	for i := range unread {
+		unread[i].PubDate = unread[i].PubDate.In(loc)
+	}
+
+	frequency := s.Frequency
+	if frequency == "off" {
+		frequency = "daily"
+	}
+	d := Data{
+		Subject:   fmt.Sprintf("Your %s ogugu digest: %d unread posts", frequency, len(unread)),
+		User:      user,
+		Posts:     unread,
+		Frequency: frequency,
+		Since:     Since(s, now).In(loc),
+	}
+	if s.UnsubscribeToken != "" {
+		d.UnsubscribeURL = b.apiURL + "/v1/digest/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken)
+	}
+
+	msg, err := Render(d)
+	return msg, len(unread), err
+}
@@ -0,0 +1,106 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+)
+
+func TestLast(t *testing.T) {
+	// Sunday 19 October 2025, 06:30 in Lagos
+	now := time.Date(2025, 10, 19, 5, 30, 0, 0, time.UTC)
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		settings models.DigestSettings
+		want     time.Time
+	}{
+		{"daily, not yet today", models.DigestSettings{Frequency: "daily", Hour: 8, Timezone: "Africa/Lagos"},
+			time.Date(2025, 10, 18, 8, 0, 0, 0, lagos)},
+		{"daily, earlier today", models.DigestSettings{Frequency: "daily", Hour: 6, Timezone: "Africa/Lagos"},
+			time.Date(2025, 10, 19, 6, 0, 0, 0, lagos)},
+		{"weekly, last monday", models.DigestSettings{Frequency: "weekly", Hour: 8, Weekday: 1, Timezone: "Africa/Lagos"},
+			time.Date(2025, 10, 13, 8, 0, 0, 0, lagos)},
+		{"weekly, later today", models.DigestSettings{Frequency: "weekly", Hour: 9, Weekday: 0, Timezone: "Africa/Lagos"},
+			time.Date(2025, 10, 12, 9, 0, 0, 0, lagos)},
+		{"weekly, earlier today", models.DigestSettings{Frequency: "weekly", Hour: 6, Weekday: 0, Timezone: "Africa/Lagos"},
+			time.Date(2025, 10, 19, 6, 0, 0, 0, lagos)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			last, err := Last(tc.settings, now)
+			require.NoError(t, err)
+			require.True(t, tc.want.Equal(last), "got %s", last)
+		})
+	}
+
+	_, err = Last(models.DigestSettings{Frequency: "daily", Timezone: "Mars/Olympus"}, now)
+	require.Error(t, err)
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2025, 10, 19, 9, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+	hourAgo := now.Add(-time.Hour)
+
+	daily := models.DigestSettings{Frequency: "daily", Hour: 8, Timezone: "UTC", UpdatedAt: yesterday}
+
+	due, err := Due(daily, now)
+	require.NoError(t, err)
+	require.True(t, due)
+
+	daily.LastSentAt = &hourAgo
+	due, err = Due(daily, now)
+	require.NoError(t, err)
+	require.False(t, due)
+
+	fresh := models.DigestSettings{Frequency: "daily", Hour: 8, Timezone: "UTC", UpdatedAt: hourAgo.Add(30 * time.Minute)}
+	due, err = Due(fresh, now)
+	require.NoError(t, err)
+	require.False(t, due)
+
+	due, err = Due(Defaults("user"), now)
+	require.NoError(t, err)
+	require.False(t, due)
+}
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Subject:   "Your daily ogugu digest: 1 unread posts",
+		User:      models.User{Username: "ada", Email: "ada@ogugu.test"},
+		Frequency: "daily",
+		Since:     time.Date(2025, 10, 18, 8, 0, 0, 0, time.UTC),
+		Posts: []models.Post{{
+			Title:     "Tom & Jerry <script>",
+			Link:      "https://example.com/post",
+			FeedTitle: "Example",
+			Summary:   "A summary",
+			PubDate:   time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC),
+			AlsoIn:    []models.AlsoIn{{FeedTitle: "Aggregator"}},
+		}},
+		UnsubscribeURL: "https://api.example/v1/digest/unsubscribe?token=abc",
+	}
+
+	msg, err := Render(data)
+	require.NoError(t, err)
+	require.Equal(t, "ada@ogugu.test", msg.To)
+	require.Contains(t, msg.HTML, "Tom &amp; Jerry &lt;script&gt;")
+	require.Contains(t, msg.HTML, `href="https://example.com/post"`)
+	require.Contains(t, msg.HTML, "also in Aggregator")
+	require.Contains(t, msg.HTML, `href="https://api.example/v1/digest/unsubscribe?token=abc"`)
+	require.Contains(t, msg.Text, "Tom & Jerry <script>\n")
+	require.Contains(t, msg.Text, "is the post you haven't read since Sat, 18 Oct 2025 08:00")
+	require.Contains(t, msg.Text, "Unsubscribe: https://api.example/v1/digest/unsubscribe?token=abc")
+
+	data.Posts = nil
+	data.UnsubscribeURL = ""
+	msg, err = Render(data)
+	require.NoError(t, err)
+	require.Contains(t, msg.Text, "There are no unread posts")
+	require.NotContains(t, msg.HTML, "Unsubscribe")
+}
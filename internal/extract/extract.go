@@ -0,0 +1,214 @@
+// Package extract pulls the main article out of a web page, for feeds that
+// only publish a teaser.
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"ogugu/internal/config"
+	"ogugu/internal/safehttp"
+	"ogugu/internal/sanitize"
+)
+
+var tracer = otel.Tracer("extract")
+
+var (
+	// ErrNotHTML is returned for pages that are not html documents.
+	ErrNotHTML = errors.New("page is not html")
+	// ErrTooLarge is returned for pages bigger than the configured cap.
+	ErrTooLarge = errors.New("page is too large")
+	// ErrNoContent is returned when no article could be found on a page.
+	ErrNoContent = errors.New("no article content found")
+)
+
+type Config struct {
+	// MaxPageSize caps how many bytes of a page are read.
+	MaxPageSize int64
+	// HostInterval is the minimum time between requests to the same host.
+	HostInterval time.Duration
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		MaxPageSize:  int64(config.Int("EXTRACT_MAX_PAGE_SIZE", 2<<20)),
+		HostInterval: config.Duration("EXTRACT_HOST_INTERVAL", 2*time.Second),
+	}
+}
+
+type Extractor struct {
+	client *http.Client
+	cfg    Config
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func New(client *http.Client, cfg Config) *Extractor {
+	return &Extractor{
+		client: client,
+		cfg:    cfg,
+		next:   map[string]time.Time{},
+	}
+}
+
+// Page is what Extract finds on a web page.
+type Page struct {
+	// Content is the sanitized html of the page's main content.
+	Content string
+	// Canonical is the page's rel=canonical link, or the address it was
+	// served from after redirects.
+	Canonical string
+}
+
+// Extract downloads link and returns its main content. Calls for the same
+// host are spaced out by the host interval.
+func (e *Extractor) Extract(ctx context.Context, link string) (Page, error) {
+	spanctx, span := tracer.Start(ctx, "extract article")
+	defer span.End()
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return Page{}, err
+	}
+	if err := e.wait(spanctx, u.Hostname()); err != nil {
+		return Page{}, err
+	}
+
+	res, err := safehttp.Get(spanctx, e.client, link)
+	if err != nil {
+		return Page{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Page{}, fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Page{}, ErrNotHTML
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, e.cfg.MaxPageSize+1))
+	if err != nil {
+		return Page{}, err
+	}
+	if int64(len(body)) > e.cfg.MaxPageSize {
+		return Page{}, ErrTooLarge
+	}
+
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return Page{}, err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Page{}, err
+	}
+	base := res.Request.URL.String()
+	canonical := Canonical(doc, base)
+	content, err := Article(doc, base)
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{Content: content, Canonical: canonical}, nil
+}
+
+// wait blocks until a request to host is allowed and reserves the next slot.
+func (e *Extractor) wait(ctx context.Context, host string) error {
+	e.mu.Lock()
+	now := time.Now()
+	at := e.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	e.next[host] = at.Add(e.cfg.HostInterval)
+	e.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Canonical returns the http(s) link of doc's rel=canonical link element
+// resolved against base, or base when there is none.
+func Canonical(doc *html.Node, base string) string {
+	var href string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if href != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" && strings.EqualFold(attr(n, "rel"), "canonical") {
+			href = attr(n, "href")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	b, err := url.Parse(base)
+	if err != nil || href == "" {
+		return base
+	}
+	u, err := b.Parse(strings.TrimSpace(href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return base
+	}
+	return u.String()
+}
+
+const (
+	// minArticleLength is the shortest text, in bytes, accepted as an article.
+	minArticleLength = 200
+	// maxLinkDensity rejects candidates that are mostly links, like link lists.
+	maxLinkDensity = 0.5
+)
+
+// Article finds the main content of doc and returns it as sanitized html
+// with links resolved against base.
+func Article(doc *html.Node, base string) (string, error) {
+	prune(doc)
+
+	best := candidate(doc)
+	if best == nil || linkDensity(best) > maxLinkDensity {
+		return "", ErrNoContent
+	}
+
+	var b strings.Builder
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return "", err
+		}
+	}
+
+	article := sanitize.HTML(b.String(), base)
+	if len(sanitize.Text(article, 0)) < minArticleLength {
+		return "", ErrNoContent
+	}
+	return article, nil
+}
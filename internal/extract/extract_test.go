@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestExtract(t *testing.T) {
+	page, err := os.ReadFile("testdata/article.html")
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/posts/long-read":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(page)
+		case "/short":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body><p>Too short.</p></body></html>"))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	e := New(http.DefaultClient, Config{MaxPageSize: 1 << 20})
+
+	t.Run("article", func(t *testing.T) {
+		page, err := e.Extract(context.Background(), srv.URL+"/posts/long-read")
+		require.NoError(t, err)
+		require.Equal(t, srv.URL+"/posts/a-long-read", page.Canonical)
+		article := page.Content
+		require.Contains(t, article, "<p>The first paragraph of the article")
+		require.Contains(t, article, `<img src="`+srv.URL+`/posts/images/figure.png" alt="figure">`)
+		require.Contains(t, article, `href="`+srv.URL+`/more"`)
+		require.NotContains(t, article, "script")
+		require.NotContains(t, article, "popular post")
+		require.NotContains(t, article, "A comment")
+		require.NotContains(t, article, "Copyright")
+	})
+
+	t.Run("too little content", func(t *testing.T) {
+		_, err := e.Extract(context.Background(), srv.URL+"/short")
+		require.ErrorIs(t, err, ErrNoContent)
+	})
+
+	t.Run("not html", func(t *testing.T) {
+		_, err := e.Extract(context.Background(), srv.URL+"/image.png")
+		require.ErrorIs(t, err, ErrNotHTML)
+	})
+
+	t.Run("missing page", func(t *testing.T) {
+		_, err := e.Extract(context.Background(), srv.URL+"/missing")
+		require.Error(t, err)
+	})
+
+	t.Run("page too large", func(t *testing.T) {
+		small := New(http.DefaultClient, Config{MaxPageSize: 512})
+		_, err := small.Extract(context.Background(), srv.URL+"/posts/long-read")
+		require.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("requests to one host are throttled", func(t *testing.T) {
+		throttled := New(http.DefaultClient, Config{MaxPageSize: 1 << 20, HostInterval: 100 * time.Millisecond})
+		start := time.Now()
+		for range 3 {
+			_, err := throttled.Extract(context.Background(), srv.URL+"/posts/long-read")
+			require.NoError(t, err)
+		}
+		require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("throttle respects cancellation", func(t *testing.T) {
+		throttled := New(http.DefaultClient, Config{MaxPageSize: 1 << 20, HostInterval: time.Hour})
+		_, err := throttled.Extract(context.Background(), srv.URL+"/posts/long-read")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = throttled.Extract(ctx, srv.URL+"/posts/long-read")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"relative", `<link rel="canonical" href="/story">`, "https://example.com/story"},
+		{"absolute", `<link rel="Canonical" href="https://origin.example/story">`, "https://origin.example/story"},
+		{"missing", `<title>x</title>`, "https://example.com/feed/story?x=1"},
+		{"not http", `<link rel="canonical" href="javascript:alert(1)">`, "https://example.com/feed/story?x=1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><head>" + tc.page + "</head></html>"))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, Canonical(doc, "https://example.com/feed/story?x=1"))
+		})
+	}
+}
+
+func TestArticleIgnoresLinkLists(t *testing.T) {
+	links := strings.Repeat(`<p><a href="/x">a link with a long enough title to be scored</a></p>`, 10)
+	_, err := extractPage(t, `<html><body><div class="links">`+links+`</div></body></html>`)
+	require.ErrorIs(t, err, ErrNoContent)
+}
+
+func extractPage(t *testing.T, page string) (Page, error) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(srv.Close)
+	return New(http.DefaultClient, Config{MaxPageSize: 1 << 20}).Extract(context.Background(), srv.URL)
+}
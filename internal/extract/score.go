@@ -0,0 +1,184 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|comment|cookie|disqus|footer|header|menu|modal|nav|popup|promo|related|share|sidebar|social|sponsor|subscribe|widget`)
+	likely   = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|story|text|blog`)
+	negative = regexp.MustCompile(`(?i)ad-|advert|comment|footer|masthead|meta|nav|outbrain|promo|related|share|shoutbox|sidebar|sponsor|widget`)
+)
+
+// boilerplate elements never hold the article.
+var boilerplate = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Header:   true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+}
+
+// prune removes boilerplate and elements whose class or id marks them as
+// page chrome rather than content.
+func prune(doc *html.Node) {
+	var remove []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.CommentNode {
+			remove = append(remove, n)
+			return
+		}
+		if n.Type == html.ElementNode {
+			if boilerplate[n.DataAtom] {
+				remove = append(remove, n)
+				return
+			}
+			names := attr(n, "class") + " " + attr(n, "id")
+			if n.DataAtom != atom.Body && n.DataAtom != atom.Article && n.DataAtom != atom.Main &&
+				unlikely.MatchString(names) && !likely.MatchString(names) {
+				remove = append(remove, n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// candidate scores the ancestors of every paragraph and returns the one
+// most likely to be the article, or nil.
+func candidate(doc *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var order []*html.Node
+
+	add := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := strings.TrimSpace(innerText(n))
+			if len(text) >= 25 {
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				add(n.Parent, score)
+				if n.Parent != nil {
+					add(n.Parent.Parent, score/2)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	var top float64
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > top {
+			best, top = n, score
+		}
+	}
+	return best
+}
+
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score = 10
+	case atom.Div:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negative.MatchString(name) {
+			score -= 25
+		}
+		if positive.MatchString(name) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of a node's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linked += len(innerText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
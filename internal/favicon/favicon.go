@@ -0,0 +1,215 @@
+// Package favicon finds and downloads the icon of a website.
+package favicon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"ogugu/internal/safehttp"
+)
+
+var tracer = otel.Tracer("favicon")
+
+const (
+	// maxPageSize caps how much of a site's page is read looking for icons.
+	maxPageSize = 512 << 10
+	// MaxIconSize is the largest icon that is stored.
+	MaxIconSize = 256 << 10
+)
+
+// ErrNotFound is returned when a site has no usable icon.
+var ErrNotFound = errors.New("no icon found")
+
+// types are the icon formats that are stored. svg is left out because it
+// can carry script.
+var types = map[string]bool{
+	"image/png":                true,
+	"image/gif":                true,
+	"image/jpeg":               true,
+	"image/webp":               true,
+	"image/x-icon":             true,
+	"image/vnd.microsoft.icon": true,
+}
+
+type Icon struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// Discover returns the icon of the site at link. hint is tried first when
+// set, then the icons the page links to, largest first, then /favicon.ico.
+func Discover(ctx context.Context, client *http.Client, link, hint string) (Icon, error) {
+	spanctx, span := tracer.Start(ctx, "discover favicon")
+	defer span.End()
+
+	site, err := url.Parse(link)
+	if err != nil {
+		return Icon{}, err
+	}
+
+	var candidates []string
+	if hint != "" {
+		candidates = append(candidates, hint)
+	}
+	if links, err := pageIcons(spanctx, client, site); err == nil {
+		candidates = append(candidates, links...)
+	}
+	candidates = append(candidates, site.ResolveReference(&url.URL{Path: "/favicon.ico"}).String())
+
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if icon, err := fetch(spanctx, client, c); err == nil {
+			return icon, nil
+		}
+	}
+	return Icon{}, ErrNotFound
+}
+
+type iconLink struct {
+	href string
+	size int
+	rank int
+}
+
+// pageIcons returns the icons linked from the page at site, best first.
+func pageIcons(ctx context.Context, client *http.Client, site *url.URL) ([]string, error) {
+	res, err := safehttp.Get(ctx, client, site.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+
+	doc, err := html.Parse(io.LimitReader(res.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	base := res.Request.URL
+
+	var links []iconLink
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Base:
+				if ref, err := url.Parse(attr(n, "href")); err == nil && attr(n, "href") != "" {
+					base = base.ResolveReference(ref)
+				}
+			case atom.Link:
+				if l, ok := parseLink(n, base); ok {
+					links = append(links, l)
+				}
+			case atom.Body:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	// rel=icon before apple-touch-icon, then the largest declared size
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].rank != links[j].rank {
+			return links[i].rank < links[j].rank
+		}
+		return links[i].size > links[j].size
+	})
+
+	hrefs := make([]string, len(links))
+	for i, l := range links {
+		hrefs[i] = l.href
+	}
+	return hrefs, nil
+}
+
+func parseLink(n *html.Node, base *url.URL) (iconLink, bool) {
+	rank := -1
+	for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+		switch rel {
+		case "icon":
+			rank = 0
+		case "apple-touch-icon", "apple-touch-icon-precomposed":
+			if rank < 0 {
+				rank = 1
+			}
+		}
+	}
+	href := strings.TrimSpace(attr(n, "href"))
+	if rank < 0 || href == "" || strings.Contains(strings.ToLower(attr(n, "type")), "svg") {
+		return iconLink{}, false
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return iconLink{}, false
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return iconLink{}, false
+	}
+
+	// sizes looks like "32x32" or "16x16 32x32" or "any"
+	var size int
+	for _, s := range strings.Fields(attr(n, "sizes")) {
+		w, _, _ := strings.Cut(strings.ToLower(s), "x")
+		if v, err := strconv.Atoi(w); err == nil && v > size {
+			size = v
+		}
+	}
+	return iconLink{href: abs.String(), size: size, rank: rank}, true
+}
+
+func fetch(ctx context.Context, client *http.Client, link string) (Icon, error) {
+	res, err := safehttp.Get(ctx, client, link)
+	if err != nil {
+		return Icon{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Icon{}, fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, MaxIconSize+1))
+	if err != nil {
+		return Icon{}, err
+	}
+	if len(data) == 0 || len(data) > MaxIconSize {
+		return Icon{}, ErrNotFound
+	}
+
+	// the declared type is often wrong for icons, so trust the bytes
+	contentType := http.DetectContentType(data)
+	if !types[contentType] {
+		return Icon{}, ErrNotFound
+	}
+	return Icon{URL: res.Request.URL.String(), ContentType: contentType, Data: data}, nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
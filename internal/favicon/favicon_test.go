@@ -0,0 +1,84 @@
+package favicon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x10")
+	ico = []byte("\x00\x00\x01\x00\x01\x00\x10\x10")
+	svg = []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+)
+
+func server(t *testing.T, page string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/small.png", func(w http.ResponseWriter, r *http.Request) { w.Write(png[:8]) })
+	mux.HandleFunc("/big.png", func(w http.ResponseWriter, r *http.Request) { w.Write(png) })
+	mux.HandleFunc("/apple.png", func(w http.ResponseWriter, r *http.Request) { w.Write(png) })
+	mux.HandleFunc("/icon.svg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(svg)
+	})
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) { w.Write(ico) })
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDiscover(t *testing.T) {
+	t.Run("largest linked icon", func(t *testing.T) {
+		srv := server(t, `<html><head>
+			<link rel="apple-touch-icon" sizes="180x180" href="/apple.png">
+			<link rel="icon" type="image/svg+xml" href="/icon.svg">
+			<link rel="icon" sizes="16x16" href="/small.png">
+			<link rel="shortcut icon" sizes="32x32" href="big.png">
+		</head><body></body></html>`)
+
+		icon, err := Discover(context.Background(), http.DefaultClient, srv.URL+"/", "")
+		require.NoError(t, err)
+		require.Equal(t, srv.URL+"/big.png", icon.URL)
+		require.Equal(t, "image/png", icon.ContentType)
+		require.Equal(t, png, icon.Data)
+	})
+
+	t.Run("hint first", func(t *testing.T) {
+		srv := server(t, `<link rel="icon" href="/big.png">`)
+		icon, err := Discover(context.Background(), http.DefaultClient, srv.URL, srv.URL+"/apple.png")
+		require.NoError(t, err)
+		require.Equal(t, srv.URL+"/apple.png", icon.URL)
+	})
+
+	t.Run("fall back to favicon.ico", func(t *testing.T) {
+		srv := server(t, `<html><head><link rel="icon" href="/missing.png"></head></html>`)
+		icon, err := Discover(context.Background(), http.DefaultClient, srv.URL+"/blog/", "")
+		require.NoError(t, err)
+		require.Equal(t, srv.URL+"/favicon.ico", icon.URL)
+		require.Equal(t, "image/x-icon", icon.ContentType)
+	})
+
+	t.Run("svg is not stored", func(t *testing.T) {
+		_, err := fetch(context.Background(), http.DefaultClient, server(t, "").URL+"/icon.svg")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("no icon", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		t.Cleanup(srv.Close)
+		_, err := Discover(context.Background(), http.DefaultClient, srv.URL, "")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
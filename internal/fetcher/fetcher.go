@@ -0,0 +1,139 @@
+// Package fetcher downloads feeds and normalizes RSS 2.0, RSS 1.0 and Atom
+// documents into a single model.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("fetcher")
+
+var (
+	// ErrNotFeed is returned for documents that are not a feed we understand.
+	ErrNotFeed = errors.New("document is not an RSS or Atom feed")
+	// ErrStatus is returned for responses other than 200 and 304.
+	ErrStatus = errors.New("unexpected response status")
+)
+
+// Feed is a parsed feed. Icon is set when an atom feed declares its favicon.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Image       string
+	Icon        string
+	Language    string
+	Generator   string
+	Copyright   string
+	Updated     time.Time
+	Podcast     *Podcast
+	Items       []Item
+}
+
+// Item holds sanitized html in Description and Content, and a plain text
+// Summary for list views.
+type Item struct {
+	GUID        string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Summary     string
+	Thumbnail   string
+	Authors     []string
+	Categories  []string
+	Published   time.Time
+	Enclosures  []Enclosure
+	Episode     *Episode
+}
+
+// Validators are the cache validators of a response, sent back on the next
+// fetch so unchanged feeds can be answered with 304 Not Modified.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+type Result struct {
+	Feed       Feed
+	Validators Validators
+	// NotModified is set when the feed is unchanged since the validators
+	// passed to Fetch. Feed is empty in that case.
+	NotModified bool
+	// URL is where the feed was found after following redirects.
+	URL string
+}
+
+type Fetcher interface {
+	Fetch(ctx context.Context, url string, v Validators) (Result, error)
+}
+
+// HTTP fetches feeds over http. The client decides which urls may be
+// fetched and how large responses may be.
+type HTTP struct {
+	client *http.Client
+}
+
+func New(client *http.Client) *HTTP {
+	return &HTTP{client: client}
+}
+
+func (f *HTTP) Fetch(ctx context.Context, url string, v Validators) (Result, error) {
+	spanctx, span := tracer.Start(ctx, "fetch feed")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(spanctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/rdf+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.5")
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer res.Body.Close()
+
+	result := Result{
+		URL: res.Request.URL.String(),
+		Validators: Validators{
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		},
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		// a 304 may omit validators that have not changed
+		if result.Validators.ETag == "" {
+			result.Validators.ETag = v.ETag
+		}
+		if result.Validators.LastModified == "" {
+			result.Validators.LastModified = v.LastModified
+		}
+		result.NotModified = true
+		return result, nil
+	default:
+		return Result{}, fmt.Errorf("%w: %s", ErrStatus, res.Status)
+	}
+
+	feed, err := Parse(res.Body, result.URL)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Feed = feed
+	return result, nil
+}
@@ -0,0 +1,208 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, fixture string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		body, err := os.ReadFile("testdata/" + fixture)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 06 Oct 2025 10:00:00 GMT")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetch(t *testing.T) {
+	f := New(http.DefaultClient)
+
+	t.Run("rss", func(t *testing.T) {
+		srv := serve(t, "rss.xml")
+		res, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.NoError(t, err)
+		require.False(t, res.NotModified)
+		require.Equal(t, Validators{ETag: `"v1"`, LastModified: "Mon, 06 Oct 2025 10:00:00 GMT"}, res.Validators)
+
+		feed := res.Feed
+		require.Equal(t, "Example Blog", feed.Title)
+		require.Equal(t, "https://example.test/", feed.Link)
+		require.Equal(t, "en-us", feed.Language)
+		require.Equal(t, "Hugo", feed.Generator)
+		require.Equal(t, "© 2025 Example", feed.Copyright)
+		require.Equal(t, time.Date(2025, 10, 6, 10, 0, 0, 0, time.UTC), feed.Updated)
+		require.Len(t, feed.Items, 2)
+		require.Equal(t, "post-2", feed.Items[0].GUID)
+		require.Equal(t, srv.URL+"/posts/second", feed.Items[0].Link)
+		require.Equal(t, time.Date(2025, 10, 6, 9, 0, 0, 0, time.UTC), feed.Items[0].Published)
+		require.Equal(t,
+			`<p>Full <a href="`+srv.URL+`/about" rel="noopener noreferrer nofollow">text</a></p><img src="`+srv.URL+`/posts/images/chart.png" alt="chart">`,
+			feed.Items[0].Content,
+		)
+		require.Equal(t, "The second post", feed.Items[0].Summary)
+		require.Equal(t, []string{"John Smith", "Jane Doe"}, feed.Items[0].Authors)
+		require.Equal(t, []string{"Golang", "Feeds"}, feed.Items[0].Categories)
+		require.Empty(t, feed.Items[1].Authors)
+		require.Equal(t, "https://example.test/posts/first", feed.Items[1].GUID)
+		require.Equal(t, "<p>The first post</p>", feed.Items[1].Description)
+		require.Equal(t, "The first post", feed.Items[1].Summary)
+		require.Empty(t, feed.Items[1].Content)
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		srv := serve(t, "atom.xml")
+		res, err := f.Fetch(context.Background(), srv.URL+"/feed.xml", Validators{})
+		require.NoError(t, err)
+
+		feed := res.Feed
+		require.Equal(t, "Example Atom", feed.Title)
+		require.Equal(t, "https://atom.example.test/", feed.Link)
+		require.Equal(t, "An atom feed", feed.Description)
+		require.Equal(t, "fr", feed.Language)
+		require.Equal(t, "Hugo", feed.Generator)
+		require.Equal(t, "CC BY", feed.Copyright)
+		require.Equal(t, srv.URL+"/favicon.png", feed.Icon)
+		require.Equal(t, "https://atom.example.test/logo.png", feed.Image)
+		require.Len(t, feed.Items, 2)
+		require.Equal(t, "https://atom.example.test/entries/1", feed.Items[0].Link)
+		require.Equal(t, time.Date(2025, 10, 5, 6, 0, 0, 0, time.UTC), feed.Items[0].Published)
+		require.Equal(t, "An entry summary", feed.Items[0].Description)
+		require.Equal(t, []string{"Entry Author"}, feed.Items[0].Authors)
+		require.Equal(t, []string{"Go", "web"}, feed.Items[0].Categories)
+		require.Equal(t, []string{"Atom Author"}, feed.Items[1].Authors)
+		require.Equal(t, srv.URL+"/entries/2", feed.Items[1].Link)
+		require.Equal(t, time.Date(2025, 10, 4, 8, 0, 0, 0, time.UTC), feed.Items[1].Published)
+		require.Empty(t, feed.Items[1].Description)
+		require.Equal(t, "<div><p>Entry <em>content</em></p></div>", feed.Items[1].Content)
+		require.Equal(t, "Entry content", feed.Items[1].Summary)
+	})
+
+	t.Run("podcast", func(t *testing.T) {
+		srv := serve(t, "podcast.xml")
+		res, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.NoError(t, err)
+
+		feed := res.Feed
+		require.Equal(t, srv.URL+"/cover.jpg", feed.Image)
+		require.Equal(t, &Podcast{Author: "Ada Example", Category: "Technology", Type: "episodic"}, feed.Podcast)
+		require.Len(t, feed.Items, 2)
+
+		episode := feed.Items[0]
+		require.Equal(t, []Enclosure{
+			{URL: "https://cdn.example.test/ep2.mp3", Type: "audio/mpeg", Length: 12345678, Duration: 3723},
+		}, episode.Enclosures)
+		require.Equal(t, "https://pod.example.test/episodes/thumbs/ep2.jpg", episode.Thumbnail)
+		require.Equal(t, &Episode{Duration: 3723, Number: 2, Season: 1, Type: "full", Explicit: true}, episode.Episode)
+
+		trailer := feed.Items[1]
+		require.Equal(t, []Enclosure{
+			{URL: "https://cdn.example.test/trailer.mp4", Type: "video/mp4", Duration: 90},
+		}, trailer.Enclosures)
+		require.Equal(t, "https://cdn.example.test/trailer.jpg", trailer.Thumbnail)
+		require.Equal(t, &Episode{Duration: 45, Type: "trailer"}, trailer.Episode)
+	})
+
+	t.Run("rss without media", func(t *testing.T) {
+		srv := serve(t, "rss.xml")
+		res, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.NoError(t, err)
+		require.Nil(t, res.Feed.Podcast)
+		require.Empty(t, res.Feed.Image)
+		require.Empty(t, res.Feed.Items[0].Enclosures)
+		require.Nil(t, res.Feed.Items[0].Episode)
+	})
+
+	t.Run("rdf in a legacy charset", func(t *testing.T) {
+		srv := serve(t, "rdf.xml")
+		res, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.NoError(t, err)
+		require.Equal(t, "Café RDF", res.Feed.Title)
+		require.Len(t, res.Feed.Items, 1)
+		require.Equal(t, "https://rdf.example.test/items/1", res.Feed.Items[0].GUID)
+		require.Equal(t, time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC), res.Feed.Updated)
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		srv := serve(t, "rss.xml")
+		res, err := f.Fetch(context.Background(), srv.URL, Validators{ETag: `"v1"`, LastModified: "yesterday"})
+		require.NoError(t, err)
+		require.True(t, res.NotModified)
+		require.Equal(t, Validators{ETag: `"v1"`, LastModified: "yesterday"}, res.Validators)
+		require.Empty(t, res.Feed.Items)
+	})
+
+	t.Run("broken feed", func(t *testing.T) {
+		srv := serve(t, "broken.xml")
+		_, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.ErrorIs(t, err, ErrNotFeed)
+	})
+
+	t.Run("html page", func(t *testing.T) {
+		srv := serve(t, "page.html")
+		_, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.ErrorIs(t, err, ErrNotFeed)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		srv := serve(t, "missing.xml")
+		_, err := f.Fetch(context.Background(), srv.URL, Validators{})
+		require.True(t, errors.Is(err, ErrStatus))
+	})
+}
+
+func TestParseDuration(t *testing.T) {
+	for raw, want := range map[string]int{
+		"3723":    3723,
+		"62:03":   3723,
+		"1:02:03": 3723,
+		"45.5":    45,
+		"":        0,
+		"soon":    0,
+		"1:2:3:4": 0,
+	} {
+		require.Equal(t, want, parseDuration(raw), raw)
+	}
+}
+
+func TestAuthorName(t *testing.T) {
+	for raw, want := range map[string]string{
+		"jane@example.test (Jane Doe)": "Jane Doe",
+		"Jane Doe <jane@example.test>": "Jane Doe",
+		"jane@example.test":            "jane@example.test",
+		" Jane Doe ":                   "Jane Doe",
+	} {
+		require.Equal(t, want, authorName(raw), raw)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, 10, 6, 9, 0, 0, 0, time.UTC)
+	for _, raw := range []string{
+		"Mon, 06 Oct 2025 09:00:00 +0000",
+		"Mon, 6 Oct 2025 09:00:00 GMT",
+		"2025-10-06T09:00:00Z",
+		"2025-10-06T11:00:00+02:00",
+		" 2025-10-06 09:00:00 ",
+	} {
+		require.Equal(t, want, parseDate(raw), raw)
+	}
+	require.True(t, parseDate("not a date").IsZero())
+}
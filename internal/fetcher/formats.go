@@ -0,0 +1,231 @@
+package fetcher
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type rssDocument struct {
+	Channel struct {
+		channelMedia
+		Title         string    `xml:"title"`
+		Link          []rssLink `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate"`
+		PubDate       string    `xml:"pubDate"`
+		Language      string    `xml:"language"`
+		Generator     string    `xml:"generator"`
+		Copyright     string    `xml:"copyright"`
+		Items         []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// rssLink skips atom:link elements that feeds put next to the rss link.
+type rssLink struct {
+	Text string `xml:",chardata"`
+}
+
+type rssItem struct {
+	itemMedia
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	GUID        string `xml:"guid"`
+
+	Creators   []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Authors    []string `xml:"author"`
+	Categories []string `xml:"category"`
+}
+
+func (it rssItem) authors() []string {
+	authors := append([]string{}, it.Creators...)
+	for _, a := range it.Authors {
+		authors = append(authors, authorName(a))
+	}
+	return authors
+}
+
+func (doc rssDocument) feed() Feed {
+	ch := doc.Channel
+	feed := Feed{
+		Title:       ch.Title,
+		Description: ch.Description,
+		Language:    ch.Language,
+		Generator:   ch.Generator,
+		Copyright:   ch.Copyright,
+		Updated:     parseDate(ch.LastBuildDate),
+	}
+	if feed.Updated.IsZero() {
+		feed.Updated = parseDate(ch.PubDate)
+	}
+	for _, link := range ch.Link {
+		if link.Text != "" {
+			feed.Link = link.Text
+			break
+		}
+	}
+	ch.channelMedia.apply(&feed)
+
+	for _, it := range ch.Items {
+		published := parseDate(it.PubDate)
+		if published.IsZero() {
+			published = parseDate(it.Date)
+		}
+		item := Item{
+			GUID:        it.GUID,
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Description,
+			Content:     it.Content,
+			Published:   published,
+			Authors:     it.authors(),
+			Categories:  it.Categories,
+		}
+		it.itemMedia.apply(&item)
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+type atomFeed struct {
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle"`
+	Updated   string       `xml:"updated"`
+	Lang      string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Generator string       `xml:"generator"`
+	Rights    atomText     `xml:"rights"`
+	Icon      string       `xml:"icon"`
+	Logo      string       `xml:"logo"`
+	Links     []atomLink   `xml:"link"`
+	Authors   []atomPerson `xml:"author"`
+	Entries   []atomEntry  `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type atomEntry struct {
+	itemMedia
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text as html. xhtml content is inline markup rather
+// than escaped text.
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// alternate returns the link to the html version of a feed or entry.
+func alternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func (doc atomFeed) feed() Feed {
+	feed := Feed{
+		Title:       doc.Title,
+		Link:        alternate(doc.Links),
+		Description: doc.Subtitle,
+		Image:       doc.Logo,
+		Icon:        doc.Icon,
+		Language:    doc.Lang,
+		Generator:   doc.Generator,
+		Copyright:   doc.Rights.String(),
+		Updated:     parseDate(doc.Updated),
+	}
+
+	for _, e := range doc.Entries {
+		published := parseDate(e.Published)
+		if published.IsZero() {
+			published = parseDate(e.Updated)
+		}
+		item := Item{
+			GUID:        e.ID,
+			Title:       e.Title,
+			Link:        alternate(e.Links),
+			Description: e.Summary.String(),
+			Content:     e.Content.String(),
+			Published:   published,
+			Authors:     atomAuthors(e.Authors),
+			Categories:  atomCategories(e.Categories),
+		}
+		// entries without an author inherit the feed's
+		if len(item.Authors) == 0 {
+			item.Authors = atomAuthors(doc.Authors)
+		}
+		var enclosures []Enclosure
+		for _, l := range e.Links {
+			if l.Rel == "enclosure" {
+				enclosures = append(enclosures, Enclosure{URL: l.Href, Type: l.Type, Length: parseInt64(l.Length)})
+			}
+		}
+		e.itemMedia.apply(&item, enclosures...)
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+// rdfDocument is RSS 1.0, where items are siblings of the channel.
+type rdfDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Rights      string `xml:"http://purl.org/dc/elements/1.1/ rights"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+func (doc rdfDocument) feed() Feed {
+	feed := Feed{
+		Title:       doc.Channel.Title,
+		Link:        doc.Channel.Link,
+		Description: doc.Channel.Description,
+		Language:    doc.Channel.Language,
+		Copyright:   doc.Channel.Rights,
+		Updated:     parseDate(doc.Channel.Date),
+	}
+	for _, it := range doc.Items {
+		item := Item{
+			GUID:        it.Link,
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Description,
+			Content:     it.Content,
+			Published:   parseDate(it.Date),
+			Authors:     it.authors(),
+			Categories:  it.Categories,
+		}
+		it.itemMedia.apply(&item)
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
@@ -0,0 +1,220 @@
+package fetcher
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Enclosure is a media file attached to an item. Duration is in seconds.
+type Enclosure struct {
+	URL      string
+	Type     string
+	Length   int64
+	Duration int
+}
+
+// Episode is the itunes metadata of a podcast episode. Duration is in
+// seconds.
+type Episode struct {
+	Duration int
+	Number   int
+	Season   int
+	Type     string
+	Explicit bool
+}
+
+// Podcast is the itunes metadata of a podcast feed.
+type Podcast struct {
+	Author   string
+	Category string
+	Type     string
+	Explicit bool
+}
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// itemMedia holds the enclosure, media rss and itunes elements of an item.
+// It is embedded first so namespaced elements match before plain ones.
+type itemMedia struct {
+	MediaContent    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []struct {
+		Content    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+
+	ITunesImage       itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesDuration    string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode     string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesSeason      string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	ITunesEpisodeType string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType"`
+	ITunesExplicit    string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+
+	Enclosures []struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"enclosure"`
+}
+
+// apply copies the media of an item onto it. Atom enclosure links are
+// passed in as extra enclosures.
+func (m itemMedia) apply(item *Item, extra ...Enclosure) {
+	for _, e := range m.Enclosures {
+		item.Enclosures = appendEnclosure(item.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: parseInt64(e.Length)})
+	}
+	for _, e := range extra {
+		item.Enclosures = appendEnclosure(item.Enclosures, e)
+	}
+
+	contents := m.MediaContent
+	thumbnails := m.MediaThumbnails
+	for _, g := range m.MediaGroups {
+		contents = append(contents, g.Content...)
+		thumbnails = append(thumbnails, g.Thumbnails...)
+	}
+	for _, c := range contents {
+		// images in media:content are previews, not attachments
+		if c.Medium == "image" || strings.HasPrefix(c.Type, "image/") {
+			thumbnails = append(thumbnails, mediaThumbnail{URL: c.URL})
+			continue
+		}
+		item.Enclosures = appendEnclosure(item.Enclosures, Enclosure{
+			URL:      c.URL,
+			Type:     c.Type,
+			Length:   parseInt64(c.FileSize),
+			Duration: int(parseInt64(c.Duration)),
+		})
+	}
+
+	for _, t := range thumbnails {
+		if t.URL != "" {
+			item.Thumbnail = t.URL
+			break
+		}
+	}
+	if item.Thumbnail == "" {
+		item.Thumbnail = m.ITunesImage.Href
+	}
+
+	if m.ITunesDuration == "" && m.ITunesEpisode == "" && m.ITunesSeason == "" &&
+		m.ITunesEpisodeType == "" && m.ITunesExplicit == "" {
+		return
+	}
+	item.Episode = &Episode{
+		Duration: parseDuration(m.ITunesDuration),
+		Number:   int(parseInt64(m.ITunesEpisode)),
+		Season:   int(parseInt64(m.ITunesSeason)),
+		Type:     strings.ToLower(strings.TrimSpace(m.ITunesEpisodeType)),
+		Explicit: parseExplicit(m.ITunesExplicit),
+	}
+	if len(item.Enclosures) > 0 && item.Enclosures[0].Duration == 0 {
+		item.Enclosures[0].Duration = item.Episode.Duration
+	}
+}
+
+// appendEnclosure skips enclosures without a url and ones already listed,
+// which feeds often repeat as both enclosure and media:content.
+func appendEnclosure(list []Enclosure, e Enclosure) []Enclosure {
+	e.URL = strings.TrimSpace(e.URL)
+	if e.URL == "" {
+		return list
+	}
+	for i, existing := range list {
+		if existing.URL == e.URL {
+			if list[i].Type == "" {
+				list[i].Type = e.Type
+			}
+			if list[i].Length == 0 {
+				list[i].Length = e.Length
+			}
+			if list[i].Duration == 0 {
+				list[i].Duration = e.Duration
+			}
+			return list
+		}
+	}
+	return append(list, e)
+}
+
+// channelMedia holds the image and itunes elements of a channel.
+type channelMedia struct {
+	ITunesImage    itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesAuthor   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	ITunesType     string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd type"`
+	ITunesExplicit string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	ITunesCategory []struct {
+		Text string `xml:"text,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+}
+
+func (m channelMedia) apply(feed *Feed) {
+	feed.Image = m.ITunesImage.Href
+	if feed.Image == "" {
+		feed.Image = m.Image.URL
+	}
+
+	if m.ITunesAuthor == "" && m.ITunesType == "" && m.ITunesExplicit == "" && len(m.ITunesCategory) == 0 {
+		return
+	}
+	feed.Podcast = &Podcast{
+		Author:   strings.TrimSpace(m.ITunesAuthor),
+		Type:     strings.ToLower(strings.TrimSpace(m.ITunesType)),
+		Explicit: parseExplicit(m.ITunesExplicit),
+	}
+	if len(m.ITunesCategory) > 0 {
+		feed.Podcast.Category = m.ITunesCategory[0].Text
+	}
+}
+
+func parseInt64(raw string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseDuration reads itunes durations, given either in seconds or as
+// HH:MM:SS or MM:SS.
+func parseDuration(raw string) int {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	var seconds int
+	for _, p := range parts {
+		n, err := strconv.ParseFloat(p, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + int(n)
+	}
+	return seconds
+}
+
+func parseExplicit(raw string) bool {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "yes", "true", "explicit":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"ogugu/internal/sanitize"
+)
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom document. Relative links are
+// resolved against base, the url the document was fetched from.
+func Parse(r io.Reader, base string) (Feed, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	root, err := rootElement(dec)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	var feed Feed
+	switch {
+	case root.Name.Local == "rss":
+		var doc rssDocument
+		if err := dec.DecodeElement(&doc, &root); err != nil {
+			return Feed{}, fmt.Errorf("%w: %w", ErrNotFeed, err)
+		}
+		feed = doc.feed()
+	case root.Name.Local == "feed" && root.Name.Space == atomNS:
+		var doc atomFeed
+		if err := dec.DecodeElement(&doc, &root); err != nil {
+			return Feed{}, fmt.Errorf("%w: %w", ErrNotFeed, err)
+		}
+		feed = doc.feed()
+	case root.Name.Local == "RDF":
+		var doc rdfDocument
+		if err := dec.DecodeElement(&doc, &root); err != nil {
+			return Feed{}, fmt.Errorf("%w: %w", ErrNotFeed, err)
+		}
+		feed = doc.feed()
+	default:
+		return Feed{}, fmt.Errorf("%w: unexpected root element <%s>", ErrNotFeed, root.Name.Local)
+	}
+
+	normalize(&feed, base)
+	return feed, nil
+}
+
+func rootElement(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, fmt.Errorf("%w: %w", ErrNotFeed, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// summaryLength is the length in runes of item summaries.
+const summaryLength = 280
+
+// normalize trims text, resolves links and fills in fields feeds commonly
+// leave out.
+func normalize(feed *Feed, base string) {
+	baseURL, _ := url.Parse(base)
+
+	feed.Title = strings.TrimSpace(feed.Title)
+	feed.Description = strings.TrimSpace(feed.Description)
+	feed.Link = resolve(baseURL, feed.Link)
+	if feed.Link == "" {
+		feed.Link = base
+	}
+	feed.Image = mediaURL(baseURL, feed.Image)
+	feed.Icon = mediaURL(baseURL, feed.Icon)
+	feed.Language = strings.ToLower(strings.TrimSpace(feed.Language))
+	feed.Generator = strings.TrimSpace(feed.Generator)
+	feed.Copyright = sanitize.Text(feed.Copyright, 0)
+
+	for i := range feed.Items {
+		item := &feed.Items[i]
+		item.Title = strings.TrimSpace(item.Title)
+		item.GUID = strings.TrimSpace(item.GUID)
+		item.Authors = uniqueNames(item.Authors)
+		item.Categories = uniqueNames(item.Categories)
+		item.Link = resolve(baseURL, item.Link)
+		if item.GUID == "" {
+			item.GUID = item.Link
+		}
+		if item.Title == "" {
+			item.Title = "Untitled"
+		}
+
+		// relative urls in an item are relative to the item itself
+		itemBase := item.Link
+		if itemBase == "" {
+			itemBase = feed.Link
+		}
+		itemBaseURL, _ := url.Parse(itemBase)
+		item.Thumbnail = mediaURL(itemBaseURL, item.Thumbnail)
+		enclosures := item.Enclosures[:0]
+		for _, e := range item.Enclosures {
+			if e.URL = mediaURL(itemBaseURL, e.URL); e.URL != "" {
+				enclosures = append(enclosures, e)
+			}
+		}
+		item.Enclosures = enclosures
+
+		item.Description = sanitize.HTML(item.Description, itemBase)
+		item.Content = sanitize.HTML(item.Content, itemBase)
+		item.Summary = sanitize.Text(item.Description, summaryLength)
+		if item.Summary == "" {
+			item.Summary = sanitize.Text(item.Content, summaryLength)
+		}
+	}
+
+	if feed.Updated.IsZero() {
+		for _, item := range feed.Items {
+			if item.Published.After(feed.Updated) {
+				feed.Updated = item.Published
+			}
+		}
+	}
+}
+
+func resolve(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" || base == nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// mediaURL resolves a media link and drops it unless it is http or https.
+func mediaURL(base *url.URL, link string) string {
+	link = resolve(base, link)
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return link
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate accepts the many date formats found in real feeds and returns
+// the zero time when none match.
+func parseDate(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,72 @@
+package fetcher
+
+import (
+	"net/mail"
+	"strings"
+)
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+func atomAuthors(people []atomPerson) []string {
+	var names []string
+	for _, p := range people {
+		if p.Name != "" {
+			names = append(names, p.Name)
+		} else {
+			names = append(names, p.Email)
+		}
+	}
+	return names
+}
+
+func atomCategories(categories []atomCategory) []string {
+	var names []string
+	for _, c := range categories {
+		if c.Label != "" {
+			names = append(names, c.Label)
+		} else {
+			names = append(names, c.Term)
+		}
+	}
+	return names
+}
+
+// authorName reads the rss author format, an email address optionally
+// followed by a name in parentheses, and returns the name when there is one.
+func authorName(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if open := strings.Index(raw, "("); open > 0 && strings.HasSuffix(raw, ")") {
+		if name := strings.TrimSpace(raw[open+1 : len(raw)-1]); name != "" {
+			return name
+		}
+	}
+	if addr, err := mail.ParseAddress(raw); err == nil && addr.Name != "" {
+		return addr.Name
+	}
+	return raw
+}
+
+// uniqueNames trims names, collapses inner whitespace and drops empty
+// entries and case-insensitive duplicates, keeping the first spelling.
+func uniqueNames(names []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, n := range names {
+		n = strings.Join(strings.Fields(n), " ")
+		key := strings.ToLower(n)
+		if n == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, n)
+	}
+	return out
+}
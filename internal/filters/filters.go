@@ -0,0 +1,151 @@
+// Package filters evaluates a user's filter rules against posts.
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ogugu/internal/models"
+)
+
+var ErrInvalidRule = errors.New("invalid filter rule")
+
+// Actions are what a user's rules do to a post.
+type Actions struct {
+	Hide bool
+	Read bool
+	Star bool
+}
+
+type rule struct {
+	field  string
+	action string
+	match  func(string) bool
+}
+
+// Set is a user's compiled rules.
+type Set struct {
+	rules []rule
+}
+
+// Compile checks and compiles rules. It returns ErrInvalidRule for an
+// unknown field, match or action, a bad regex, or a regex on a feed.
+func Compile(rules []models.FilterRule) (*Set, error) {
+	s := &Set{}
+	for _, r := range rules {
+		c, err := compile(r)
+		if err != nil {
+			return nil, err
+		}
+		s.rules = append(s.rules, c)
+	}
+	return s, nil
+}
+
+func compile(r models.FilterRule) (rule, error) {
+	switch r.Field {
+	case "title", "description", "text", "author", "category", "feed":
+	default:
+		return rule{}, fmt.Errorf("%w: unknown field %q", ErrInvalidRule, r.Field)
+	}
+	switch r.Action {
+	case "hide", "read", "star":
+	default:
+		return rule{}, fmt.Errorf("%w: unknown action %q", ErrInvalidRule, r.Action)
+	}
+
+	c := rule{field: r.Field, action: r.Action}
+	switch r.Match {
+	case "keyword":
+		pattern := fold(r.Pattern)
+		if pattern == "" {
+			return rule{}, fmt.Errorf("%w: empty keyword", ErrInvalidRule)
+		}
+		switch r.Field {
+		case "author", "category":
+			c.match = func(s string) bool { return fold(s) == pattern }
+		case "feed":
+			c.match = func(s string) bool { return s == strings.TrimSpace(r.Pattern) }
+		default:
+			c.match = func(s string) bool { return strings.Contains(fold(s), pattern) }
+		}
+	case "regex":
+		if r.Field == "feed" {
+			return rule{}, fmt.Errorf("%w: feeds are matched by id", ErrInvalidRule)
+		}
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return rule{}, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+		c.match = re.MatchString
+	default:
+		return rule{}, fmt.Errorf("%w: unknown match %q", ErrInvalidRule, r.Match)
+	}
+	return c, nil
+}
+
+// fold lowercases s and collapses its whitespace, so keywords match
+// regardless of case and spacing.
+func fold(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Match returns the actions of every rule that matches p.
+func (s *Set) Match(p models.Post) Actions {
+	var a Actions
+	for _, r := range s.rules {
+		if !slices.ContainsFunc(values(r.field, p), r.match) {
+			continue
+		}
+		switch r.action {
+		case "hide":
+			a.Hide = true
+		case "read":
+			a.Read = true
+		case "star":
+			a.Star = true
+		}
+	}
+	return a
+}
+
+// Visible returns the posts no hide rule matches.
+func (s *Set) Visible(posts []models.Post) []models.Post {
+	if len(s.rules) == 0 {
+		return posts
+	}
+	visible := []models.Post{}
+	for _, p := range posts {
+		if !s.Match(p).Hide {
+			visible = append(visible, p)
+		}
+	}
+	return visible
+}
+
+// values returns the text of p a rule on field is matched against.
+// Descriptions are matched as plain text.
+func values(field string, p models.Post) []string {
+	description := p.Summary
+	if description == "" {
+		description = p.Description
+	}
+	switch field {
+	case "title":
+		return []string{p.Title}
+	case "description":
+		return []string{description}
+	case "text":
+		return []string{p.Title, description}
+	case "author":
+		return p.Authors
+	case "category":
+		return p.Categories
+	case "feed":
+		return []string{p.RssID}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.FilterRule
+	}{
+		{"unknown field", models.FilterRule{Field: "link", Match: "keyword", Pattern: "x", Action: "hide"}},
+		{"unknown action", models.FilterRule{Field: "title", Match: "keyword", Pattern: "x", Action: "delete"}},
+		{"unknown match", models.FilterRule{Field: "title", Match: "glob", Pattern: "x", Action: "hide"}},
+		{"bad regex", models.FilterRule{Field: "title", Match: "regex", Pattern: "(", Action: "hide"}},
+		{"regex on a feed", models.FilterRule{Field: "feed", Match: "regex", Pattern: ".*", Action: "hide"}},
+		{"blank keyword", models.FilterRule{Field: "title", Match: "keyword", Pattern: "  ", Action: "hide"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile([]models.FilterRule{tc.rule})
+			require.ErrorIs(t, err, ErrInvalidRule)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	post := models.Post{
+		RssID:       "feed1",
+		Title:       "Sponsored: The Best   VPN of 2025",
+		Description: "<p>Buy now</p>",
+		Summary:     "Buy now",
+		Authors:     models.Names{"Jane Doe"},
+		Categories:  models.Names{"Go", "Ads"},
+	}
+
+	tests := []struct {
+		name string
+		rule models.FilterRule
+		want Actions
+	}{
+		{"title keyword", models.FilterRule{Field: "title", Match: "keyword", Pattern: "best vpn", Action: "hide"}, Actions{Hide: true}},
+		{"title keyword misses", models.FilterRule{Field: "title", Match: "keyword", Pattern: "rust", Action: "hide"}, Actions{}},
+		{"description as text", models.FilterRule{Field: "description", Match: "keyword", Pattern: "<p>", Action: "hide"}, Actions{}},
+		{"text regex", models.FilterRule{Field: "text", Match: "regex", Pattern: `^buy\b`, Action: "read"}, Actions{Read: true}},
+		{"author", models.FilterRule{Field: "author", Match: "keyword", Pattern: "jane  DOE", Action: "star"}, Actions{Star: true}},
+		{"author is exact", models.FilterRule{Field: "author", Match: "keyword", Pattern: "jane", Action: "star"}, Actions{}},
+		{"category regex", models.FilterRule{Field: "category", Match: "regex", Pattern: "^ads$", Action: "hide"}, Actions{Hide: true}},
+		{"feed", models.FilterRule{Field: "feed", Match: "keyword", Pattern: "feed1", Action: "read"}, Actions{Read: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Compile([]models.FilterRule{tc.rule})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, s.Match(post))
+		})
+	}
+}
+
+func TestVisible(t *testing.T) {
+	s, err := Compile([]models.FilterRule{
+		{Field: "title", Match: "keyword", Pattern: "sponsored", Action: "hide"},
+		{Field: "title", Match: "keyword", Pattern: "release", Action: "star"},
+	})
+	require.NoError(t, err)
+
+	posts := s.Visible([]models.Post{
+		{ID: "1", Title: "Sponsored post"},
+		{ID: "2", Title: "Go 1.25 release"},
+	})
+	require.Len(t, posts, 1)
+	require.Equal(t, "2", posts[0].ID)
+
+	empty, err := Compile(nil)
+	require.NoError(t, err)
+	require.Len(t, empty.Visible([]models.Post{{ID: "1"}}), 1)
+}
@@ -0,0 +1,172 @@
+// Package lockout counts failed sign in attempts in redis and locks out the
+// source of repeated failures for progressively longer periods.
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/config"
+)
+
+var tracer = otel.Tracer("lockout")
+
+type Config struct {
+	// MaxAttempts is how many failures a key may have before it is locked.
+	MaxAttempts int
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+	// Lockout is the length of the first lock. Each further failure while
+	// over the limit doubles it, up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+// Key identifies what failures are counted against, for example an ip
+// address or an account, each with its own limits.
+type Key struct {
+	Name string
+	cfg  Config
+}
+
+type Limiter struct {
+	cache   *redis.Client
+	ip      Config
+	account Config
+}
+
+// ConfigFromEnv returns the limits for ip addresses and for accounts.
+// Ip addresses get more attempts since many users can share one.
+func ConfigFromEnv() (ip, account Config) {
+	lock := config.Duration("LOGIN_LOCKOUT", time.Minute)
+	maxLock := config.Duration("LOGIN_MAX_LOCKOUT", time.Hour)
+	window := config.Duration("LOGIN_ATTEMPT_WINDOW", time.Minute*15)
+
+	ip = Config{
+		MaxAttempts: config.Int("LOGIN_MAX_IP_ATTEMPTS", 20),
+		Window:      window,
+		Lockout:     lock,
+		MaxLockout:  maxLock,
+	}
+	account = Config{
+		MaxAttempts: config.Int("LOGIN_MAX_ACCOUNT_ATTEMPTS", 5),
+		Window:      window,
+		Lockout:     lock,
+		MaxLockout:  maxLock,
+	}
+	return ip, account
+}
+
+func New(cache *redis.Client, ip, account Config) *Limiter {
+	return &Limiter{cache: cache, ip: ip, account: account}
+}
+
+// IP returns the key counting failures from addr.
+func (l *Limiter) IP(addr string) Key {
+	return Key{Name: "ip:" + addr, cfg: l.ip}
+}
+
+// Account returns the key counting failures against the account id.
+func (l *Limiter) Account(id string) Key {
+	return Key{Name: "account:" + id, cfg: l.account}
+}
+
+// TwoFactor returns the key counting wrong two-factor codes entered by the
+// signed in user id. It shares the account limits.
+func (l *Limiter) TwoFactor(userID string) Key {
+	return Key{Name: "2fa:" + userID, cfg: l.account}
+}
+
+func failuresKey(k Key) string {
+	return "login:failures:" + k.Name
+}
+
+func lockKey(k Key) string {
+	return "login:lock:" + k.Name
+}
+
+// Locked returns how long the longest lock on keys has left, or zero when
+// none of them are locked.
+func (l *Limiter) Locked(ctx context.Context, keys ...Key) (time.Duration, error) {
+	spanctx, span := tracer.Start(ctx, "check lockout")
+	defer span.End()
+
+	pipe := l.cache.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, k := range keys {
+		ttls[i] = pipe.PTTL(spanctx, lockKey(k))
+	}
+	if _, err := pipe.Exec(spanctx); err != nil {
+		return 0, err
+	}
+
+	var longest time.Duration
+	for _, ttl := range ttls {
+		// missing keys report a negative ttl
+		if d := ttl.Val(); d > longest {
+			longest = d
+		}
+	}
+	return longest, nil
+}
+
+// Fail records a failed attempt against every key, locking those that went
+// over their limit.
+func (l *Limiter) Fail(ctx context.Context, keys ...Key) error {
+	spanctx, span := tracer.Start(ctx, "record failed attempt")
+	defer span.End()
+
+	pipe := l.cache.TxPipeline()
+	counts := make([]*redis.IntCmd, len(keys))
+	for i, k := range keys {
+		counts[i] = pipe.Incr(spanctx, failuresKey(k))
+		pipe.Expire(spanctx, failuresKey(k), k.cfg.Window)
+	}
+	if _, err := pipe.Exec(spanctx); err != nil {
+		return err
+	}
+
+	pipe = l.cache.TxPipeline()
+	locked := false
+	for i, k := range keys {
+		over := int(counts[i].Val()) - k.cfg.MaxAttempts
+		if over < 0 {
+			continue
+		}
+		locked = true
+		pipe.Set(spanctx, lockKey(k), 1, lockDuration(k.cfg, over))
+	}
+	if !locked {
+		return nil
+	}
+	_, err := pipe.Exec(spanctx)
+	return err
+}
+
+// Reset forgets the failures recorded against keys, for example after a
+// successful sign in.
+func (l *Limiter) Reset(ctx context.Context, keys ...Key) error {
+	spanctx, span := tracer.Start(ctx, "reset failed attempts")
+	defer span.End()
+
+	names := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		names = append(names, failuresKey(k), lockKey(k))
+	}
+	return l.cache.Del(spanctx, names...).Err()
+}
+
+// lockDuration doubles the lockout for every failure over the limit.
+func lockDuration(cfg Config, over int) time.Duration {
+	d := cfg.Lockout
+	for range over {
+		d *= 2
+		if d >= cfg.MaxLockout {
+			return cfg.MaxLockout
+		}
+	}
+	return min(d, cfg.MaxLockout)
+}
@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/database/cache/cachetest"
+)
+
+func TestLimiter(t *testing.T) {
+	rds, teardown := cachetest.SetupTestCache(t)
+	t.Cleanup(teardown)
+
+	cfg := Config{MaxAttempts: 3, Window: time.Minute, Lockout: time.Second * 10, MaxLockout: time.Second * 30}
+	l := New(rds, Config{MaxAttempts: 10, Window: time.Minute, Lockout: time.Second, MaxLockout: time.Second}, cfg)
+	ctx := context.Background()
+	ip, account := l.IP("127.0.0.1"), l.Account("userid")
+
+	t.Run("not locked below the limit", func(t *testing.T) {
+		for range 2 {
+			require.NoError(t, l.Fail(ctx, ip, account))
+		}
+		d, err := l.Locked(ctx, ip, account)
+		require.NoError(t, err)
+		require.Zero(t, d)
+	})
+
+	t.Run("locked at the limit", func(t *testing.T) {
+		require.NoError(t, l.Fail(ctx, ip, account))
+		d, err := l.Locked(ctx, ip, account)
+		require.NoError(t, err)
+		require.InDelta(t, time.Second*10, d, float64(time.Second))
+
+		d, err = l.Locked(ctx, ip)
+		require.NoError(t, err)
+		require.Zero(t, d, "the ip limit is higher")
+	})
+
+	t.Run("lockout grows", func(t *testing.T) {
+		require.NoError(t, l.Fail(ctx, account))
+		d, err := l.Locked(ctx, account)
+		require.NoError(t, err)
+		require.InDelta(t, time.Second*20, d, float64(time.Second))
+
+		require.NoError(t, l.Fail(ctx, account))
+		d, err = l.Locked(ctx, account)
+		require.NoError(t, err)
+		require.InDelta(t, time.Second*30, d, float64(time.Second))
+	})
+
+	t.Run("two factor is counted apart from sign in", func(t *testing.T) {
+		d, err := l.Locked(ctx, l.TwoFactor("userid"))
+		require.NoError(t, err)
+		require.Zero(t, d)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		require.NoError(t, l.Reset(ctx, account))
+		d, err := l.Locked(ctx, account)
+		require.NoError(t, err)
+		require.Zero(t, d)
+	})
+}
+
+func TestLockDuration(t *testing.T) {
+	cfg := Config{Lockout: time.Minute, MaxLockout: time.Minute * 5}
+	require.Equal(t, time.Minute, lockDuration(cfg, 0))
+	require.Equal(t, time.Minute*2, lockDuration(cfg, 1))
+	require.Equal(t, time.Minute*4, lockDuration(cfg, 2))
+	require.Equal(t, time.Minute*5, lockDuration(cfg, 3))
+	require.Equal(t, time.Minute*5, lockDuration(cfg, 100))
+}
@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"ogugu/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers transactional emails such as password reset links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the mailer selected by the MAIL_DRIVER env variable.
+// Supported drivers are "smtp", "log" and "memory"; "log" is the default so
+// local setups work without an SMTP server.
+func New(log *zap.Logger) (Mailer, error) {
+	driver := config.String("MAIL_DRIVER", "log")
+	switch driver {
+	case "smtp":
+		return NewSMTP(
+			config.String("SMTP_HOST", "localhost"),
+			config.Int("SMTP_PORT", 587),
+			config.String("SMTP_USERNAME", ""),
+			config.String("SMTP_PASSWORD", ""),
+			config.String("MAIL_FROM", "ogugu <no-reply@localhost>"),
+		), nil
+	case "log":
+		return NewLog(log), nil
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+// Log writes outgoing messages to the application logger instead of
+// delivering them.
+type Log struct {
+	log *zap.Logger
+}
+
+func NewLog(log *zap.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	l.log.Info("outgoing email",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Text),
+	)
+	return nil
+}
+
+// Memory keeps outgoing messages in memory so they can be inspected.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMailer(t *testing.T) {
+	t.Run("memory mailer records messages", func(t *testing.T) {
+		m := NewMemory()
+		err := m.Send(context.Background(), Message{To: "user@ogugu.test", Subject: "hi", Text: "hello"})
+		require.NoError(t, err)
+
+		msgs := m.Messages()
+		require.Len(t, msgs, 1)
+		require.Equal(t, "user@ogugu.test", msgs[0].To)
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		t.Setenv("MAIL_DRIVER", "pigeon")
+		_, err := New(zap.NewNop())
+		require.Error(t, err)
+	})
+
+	t.Run("default driver logs", func(t *testing.T) {
+		m, err := New(zap.NewNop())
+		require.NoError(t, err)
+		require.IsType(t, &Log{}, m)
+	})
+
+	t.Run("compose plain text message", func(t *testing.T) {
+		from := &mail.Address{Name: "ogugu", Address: "no-reply@ogugu.test"}
+		to := &mail.Address{Address: "user@ogugu.test"}
+		body, err := compose(from, to, Message{Subject: "Reset", Text: "hello"})
+		require.NoError(t, err)
+
+		s := string(body)
+		require.Contains(t, s, "Content-Type: text/plain; charset=UTF-8")
+		require.NotContains(t, s, "multipart/alternative")
+		require.True(t, strings.HasSuffix(s, "hello"))
+	})
+
+	t.Run("compose html message", func(t *testing.T) {
+		from := &mail.Address{Address: "no-reply@ogugu.test"}
+		to := &mail.Address{Address: "user@ogugu.test"}
+		body, err := compose(from, to, Message{Subject: "Reset", Text: "hello", HTML: "<p>hello</p>"})
+		require.NoError(t, err)
+
+		s := string(body)
+		require.Contains(t, s, "multipart/alternative")
+		require.Contains(t, s, "text/html; charset=UTF-8")
+		require.Contains(t, s, "<p>hello</p>")
+	})
+}
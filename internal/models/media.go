@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Enclosure is a media file attached to a post. Duration is in seconds.
+type Enclosure struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Length   int64  `json:"length"`
+	Duration int    `json:"duration"`
+}
+
+// Enclosures scans the json array the post queries aggregate enclosures into.
+type Enclosures []Enclosure
+
+func (e *Enclosures) Scan(src any) error {
+	return scanJSON(src, e)
+}
+
+// Names scans the json array of author or category names of a post.
+type Names []string
+
+func (n *Names) Scan(src any) error {
+	return scanJSON(src, n)
+}
+
+// Episode is the itunes metadata of a podcast episode. Duration is in seconds.
+type Episode struct {
+	Duration int    `json:"duration"`
+	Number   int    `json:"episode"`
+	Season   int    `json:"season"`
+	Type     string `json:"episode_type"`
+	Explicit bool   `json:"explicit"`
+}
+
+func (e *Episode) Scan(src any) error {
+	return scanJSON(src, e)
+}
+
+func (e *Episode) Value() (driver.Value, error) {
+	return valueJSON(e)
+}
+
+// Podcast is the itunes metadata of a podcast feed.
+type Podcast struct {
+	Author   string `json:"author"`
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	Explicit bool   `json:"explicit"`
+}
+
+func (p *Podcast) Scan(src any) error {
+	return scanJSON(src, p)
+}
+
+func (p *Podcast) Value() (driver.Value, error) {
+	return valueJSON(p)
+}
+
+func scanJSON(src, dst any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("cannot scan %T as json", src)
+	}
+}
+
+func valueJSON[T any](v *T) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
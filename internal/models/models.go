@@ -4,6 +4,11 @@ import "time"
 
 const AuthSessionKey = "AuthSession"
 
+const (
+	ScopeRead      = "read"
+	ScopeReadWrite = "read_write"
+)
+
 type Subscription struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
@@ -17,45 +22,91 @@ type SubscriptionBody struct {
 }
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Avatar    string    `json:"avatar"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Avatar          string     `json:"avatar"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type UserWithAuth struct {
-	User      User   `json:"user"`
-	AuthToken string `json:"auth_token"`
+	User         User      `json:"user"`
+	AuthToken    string    `json:"auth_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type TokenPair struct {
+	AuthToken    string    `json:"auth_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
 
 type RssFeed struct {
-	ID           string    `json:"id"`
-	Title        string    `json:"title"`
-	Link         string    `json:"link"`
-	Description  string    `json:"description"`
-	Fetched      bool      `json:"fetched"`
-	RSSLink      string    `json:"rss_link"`
-	LastModified time.Time `json:"last_modified"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Link          string     `json:"link"`
+	Description   string     `json:"description"`
+	Image         string     `json:"image"`
+	Language      string     `json:"language"`
+	Generator     string     `json:"generator"`
+	Copyright     string     `json:"copyright"`
+	Podcast       *Podcast   `json:"podcast,omitempty"`
+	IconCheckedAt *time.Time `json:"-"`
+	Fetched       bool       `json:"fetched"`
+	FetchFullText bool       `json:"fetch_full_text"`
+	RSSLink       string     `json:"rss_link"`
+	LastModified  *time.Time `json:"last_modified"`
+	ETag          string     `json:"-"`
+	LastFetchedAt *time.Time `json:"-"`
+	OrphanedSince *time.Time `json:"orphaned_since,omitempty"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
+// Post is a feed entry. Posts of the same story in several feeds share a
+// ClusterID, and timelines show one of them with the others in AlsoIn.
 type Post struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Link        string    `json:"link"`
-	PubDate     time.Time `json:"pubDate"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	RssID       string     `json:"rss_id"`
+	FeedTitle   string     `json:"feed_title"`
+	ClusterID   string     `json:"cluster_id"`
+	AlsoIn      []AlsoIn   `json:"also_in,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Content     string     `json:"content"`
+	FullContent string     `json:"full_content"`
+	Summary     string     `json:"summary"`
+	Link        string     `json:"link"`
+	Thumbnail   string     `json:"thumbnail"`
+	Enclosures  Enclosures `json:"enclosures"`
+	Authors     Names      `json:"authors"`
+	Categories  Names      `json:"categories"`
+	Episode     *Episode   `json:"episode,omitempty"`
+	PubDate     time.Time  `json:"pubDate"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AlsoIn is a duplicate of a post in another feed.
+type AlsoIn struct {
+	PostID    string `json:"post_id"`
+	RssID     string `json:"rss_id"`
+	FeedTitle string `json:"feed_title"`
+	Link      string `json:"link"`
 }
 
 type CreateRssBody struct {
 	Link string `json:"link" validate:"required,url"`
 }
 
+type UpdateFullTextBody struct {
+	FetchFullText *bool `json:"fetch_full_text" validate:"required"`
+}
+
 type CreateUserBody struct {
 	Username string `json:"username" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
@@ -66,33 +117,263 @@ type CreateUserBody struct {
 type SigninBody struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,max=75"`
+	// RefreshToken requests a short-lived access token paired with a
+	// rotating refresh token instead of a sliding session.
+	RefreshToken bool `json:"refresh_token"`
+}
+
+type RefreshBody struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordBody struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=75"`
 }
 
 type Session struct {
-	ID         string
-	UserID     string
-	CreatedAt  time.Time
-	ExpiryTime time.Time
+	ID                 string    `json:"id"`
+	UserID             string    `json:"user_id"`
+	UserAgent          string    `json:"user_agent"`
+	IP                 string    `json:"ip"`
+	CreatedAt          time.Time `json:"created_at"`
+	LastSeenAt         time.Time `json:"last_seen_at"`
+	ExpiryTime         time.Time `json:"expires_at"`
+	AbsoluteExpiryTime time.Time `json:"absolute_expires_at"`
+	Current            bool      `json:"current"`
+	// APIKeyID and Scope are only set when the request was authenticated
+	// with an API key rather than a session token.
+	APIKeyID string `json:"-"`
+	Scope    string `json:"-"`
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type APIKeyWithSecret struct {
+	APIKey APIKey `json:"api_key"`
+	// Key is only ever returned when the key is created.
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyBody struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Scope string `json:"scope" validate:"required,oneof=read read_write"`
 }
 
 type RSSMeta struct {
 	Channel struct {
-		LastModified string `xml:"lastBuildDate"`
-		Title        string `xml:"title" validate:"required"`
-		Description  string `xml:"description" validate:"required"`
-		Link         string `xml:"link" validate:"required"`
-	} `xml:"channel"`
+		LastModified string
+		Title        string
+		Description  string
+		Link         string
+		Image        string
+		Language     string
+		Generator    string
+		Copyright    string
+		Podcast      *Podcast
+	}
 }
 
-type RSSItems struct {
-	Channel struct {
-		Items []CreatePost `xml:"item"`
-	} `xml:"channel"`
+type FeedIcon struct {
+	URL         string
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
 }
 
 type CreatePost struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	PubDate     string `xml:"pubDate"`
+	Title       string
+	Description string
+	Content     string
+	Summary     string
+	Link        string
+	Thumbnail   string
+	Enclosures  []Enclosure
+	Authors     []string
+	Categories  []string
+	Episode     *Episode
+	PubDate     string
+
+	CanonicalURL string
+	SimHash      *uint64
+}
+
+// PostFilter narrows post listings to an author or category. Values are
+// matched by slug, so case and spacing do not matter.
+type PostFilter struct {
+	Author   string
+	Category string
+}
+
+// FeedQuery searches and orders the feed directory. Search matches titles,
+// descriptions and links. Language matches regional variants too, so "en"
+// matches "en-us". Category is matched by slug.
+type FeedQuery struct {
+	Search   string
+	Language string
+	Category string
+	Sort     string `validate:"omitempty,oneof=subscribers recent title"`
+}
+
+// DirectoryFeed is a feed with activity computed from its subscriptions and
+// the posts of the last 30 days.
+type DirectoryFeed struct {
+	RssFeed
+	Subscribers  int        `json:"subscribers"`
+	PostsPerWeek float64    `json:"posts_per_week"`
+	LastPostAt   *time.Time `json:"last_post_at"`
+}
+
+// PostState is a user's read and star marks on a post.
+type PostState struct {
+	PostID    string     `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	StarredAt *time.Time `json:"starred_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// UpdatePostStateBody marks a post read or starred. A missing field leaves
+// that mark unchanged.
+type UpdatePostStateBody struct {
+	Read    *bool `json:"read" validate:"required_without=Starred"`
+	Starred *bool `json:"starred" validate:"required_without=Read"`
+}
+
+// FeedSignals is a user's history with a feed and the feed's activity, used
+// to rank its posts. Reads and Stars count the feed's posts the user read or
+// starred out of Posts.
+type FeedSignals struct {
+	Reads        int
+	Stars        int
+	Posts        int
+	PostsPerWeek float64
+}
+
+// RankCandidate is a post that may appear in a ranked timeline.
+type RankCandidate struct {
+	Post Post
+	Feed FeedSignals
+}
+
+// FilterRule hides, marks read or stars a user's posts whose Field matches
+// Pattern. Keyword patterns match text case-insensitively and author,
+// category and feed names exactly; regex patterns use RE2 syntax.
+type FilterRule struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Field     string    `json:"field"`
+	Match     string    `json:"match"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateFilterRuleBody struct {
+	Field   string `json:"field" validate:"required,oneof=title description text author category feed"`
+	Match   string `json:"match" validate:"required,oneof=keyword regex"`
+	Pattern string `json:"pattern" validate:"required,max=256"`
+	Action  string `json:"action" validate:"required,oneof=hide read star"`
+}
+
+// DigestSettings is when a user is emailed a digest of their unread posts.
+// Hour and Weekday are in Timezone, and Weekday, with Sunday as 0, only
+// applies to weekly digests.
+type DigestSettings struct {
+	UserID           string     `json:"-"`
+	Frequency        string     `json:"frequency"`
+	Hour             int        `json:"hour"`
+	Weekday          int        `json:"weekday"`
+	Timezone         string     `json:"timezone"`
+	UnsubscribeToken string     `json:"-"`
+	LastSentAt       *time.Time `json:"last_sent_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type UpdateDigestBody struct {
+	Frequency string `json:"frequency" validate:"required,oneof=off daily weekly"`
+	Hour      *int   `json:"hour" validate:"required,min=0,max=23"`
+	Weekday   *int   `json:"weekday" validate:"omitempty,min=0,max=6"`
+	Timezone  string `json:"timezone" validate:"required,timezone"`
+}
+
+// DigestPreview is the digest a user would be sent now.
+type DigestPreview struct {
+	Subject string `json:"subject"`
+	Posts   int    `json:"posts"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// RetentionPolicy is how long posts are kept before they are pruned. Zero
+// limits keep posts forever.
+type RetentionPolicy struct {
+	MaxAge   time.Duration
+	MaxItems int
+}
+
+// FeedRetention overrides the retention policy for one feed. Nil limits
+// fall back to the global policy and zero limits keep posts forever.
+type FeedRetention struct {
+	RssID      string `json:"rss_id"`
+	MaxAgeDays *int   `json:"max_age_days"`
+	MaxItems   *int   `json:"max_items"`
+}
+
+type UpdateRetentionBody struct {
+	MaxAgeDays *int `json:"max_age_days" validate:"omitempty,min=0,max=36500"`
+	MaxItems   *int `json:"max_items" validate:"omitempty,min=0,max=1000000"`
+}
+
+// PruneReport is how many posts of a feed were, or would be, pruned.
+type PruneReport struct {
+	RssID string
+	Title string
+	Posts int
+}
+
+type TwoFactor struct {
+	UserID    string     `json:"-"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// TwoFactorCodeBody carries either a TOTP code or a recovery code.
+type TwoFactorCodeBody struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type TwoFactorSigninBody struct {
+	PendingToken string `json:"pending_token" validate:"required"`
+	Code         string `json:"code" validate:"required,max=32"`
+}
+
+// PendingTwoFactor is returned by sign in instead of a session when the
+// user still has to enter a two-factor code.
+type PendingTwoFactor struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	PendingToken      string    `json:"pending_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
 }
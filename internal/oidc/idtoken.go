@@ -0,0 +1,203 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// leeway tolerates clock drift between us and the identity provider.
+const leeway = time.Minute
+
+// keyRefreshInterval limits how often an unknown kid triggers a jwks fetch.
+const keyRefreshInterval = time.Minute
+
+// Claims are the id token claims we rely on.
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Picture           string   `json:"picture"`
+}
+
+// audience accepts both forms of the aud claim, a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type keySet struct {
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+type jwk struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// key returns the signing key named kid, refetching the provider's keys
+// when it is unknown since providers rotate keys without notice.
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keys.fetched) < keyRefreshInterval {
+		return nil, ErrInvalidIDToken
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keySet{keys: keys, fetched: time.Now()}
+
+	if key, ok := p.keys.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrInvalidIDToken
+}
+
+func (s keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	// tokens without a kid are only accepted from providers with one key
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Verify checks the signature and claims of an RS256 id token issued to us
+// for the sign in identified by nonce.
+func (p *Provider) Verify(ctx context.Context, token, nonce string) (Claims, error) {
+	spanctx, span := tracer.Start(ctx, "verify id token")
+	defer span.End()
+
+	meta, err := p.discover(spanctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	enc := base64.RawURLEncoding
+	rawHeader, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+	if header.Algorithm != "RS256" {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	key, err := p.key(spanctx, meta.JWKSURI, header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	signature, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	rawClaims, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	var c Claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	now := time.Now()
+	switch {
+	case c.Issuer != meta.Issuer:
+		return Claims{}, ErrInvalidIDToken
+	case c.Subject == "":
+		return Claims{}, ErrInvalidIDToken
+	case !slices.Contains(c.Audience, p.cfg.ClientID):
+		return Claims{}, ErrInvalidIDToken
+	case len(c.Audience) > 1 && c.AuthorizedParty != p.cfg.ClientID:
+		return Claims{}, ErrInvalidIDToken
+	case now.Add(-leeway).After(time.Unix(c.Expiry, 0)):
+		return Claims{}, ErrInvalidIDToken
+	case now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return Claims{}, ErrInvalidIDToken
+	case c.Nonce != nonce:
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	return c, nil
+}
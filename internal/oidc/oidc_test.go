@@ -0,0 +1,143 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/oidc/oidctest"
+)
+
+func TestProvider(t *testing.T) {
+	idp := oidctest.NewServer(t, oidctest.User{
+		Subject:       "subject",
+		Email:         "user@ogugu.test",
+		EmailVerified: true,
+	})
+
+	p := New(Config{
+		Issuer:       idp.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/v1/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	}, idp.Client())
+
+	// authorize follows the provider's redirect and returns the code
+	authorize := func(t *testing.T, verifier, nonce string) string {
+		authURL, err := p.AuthCodeURL(context.Background(), "state", nonce, Challenge(verifier))
+		require.NoError(t, err)
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := client.Get(authURL)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "state", location.Query().Get("state"))
+		return location.Query().Get("code")
+	}
+
+	t.Run("exchange code", func(t *testing.T) {
+		verifier, err := NewVerifier()
+		require.NoError(t, err)
+
+		code := authorize(t, verifier, "nonce")
+		claims, err := p.Exchange(context.Background(), code, verifier, "nonce")
+		require.NoError(t, err)
+		require.Equal(t, "subject", claims.Subject)
+		require.Equal(t, "user@ogugu.test", claims.Email)
+		require.True(t, claims.EmailVerified)
+	})
+
+	t.Run("exchange code with the wrong verifier", func(t *testing.T) {
+		code := authorize(t, "verifier", "nonce")
+		_, err := p.Exchange(context.Background(), code, "other verifier", "nonce")
+		require.Error(t, err)
+	})
+
+	t.Run("exchange code with the wrong nonce", func(t *testing.T) {
+		code := authorize(t, "verifier", "nonce")
+		_, err := p.Exchange(context.Background(), code, "verifier", "other nonce")
+		require.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+
+	t.Run("exchange code twice", func(t *testing.T) {
+		code := authorize(t, "verifier", "nonce")
+		_, err := p.Exchange(context.Background(), code, "verifier", "nonce")
+		require.NoError(t, err)
+		_, err = p.Exchange(context.Background(), code, "verifier", "nonce")
+		require.Error(t, err)
+	})
+
+	valid := func() map[string]any {
+		now := time.Now()
+		return map[string]any{
+			"iss":   idp.URL,
+			"sub":   "subject",
+			"aud":   oidctest.ClientID,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	t.Run("verify token", func(t *testing.T) {
+		_, err := p.Verify(context.Background(), idp.Sign(valid()), "nonce")
+		require.NoError(t, err)
+	})
+
+	t.Run("verify token with audience list", func(t *testing.T) {
+		claims := valid()
+		claims["aud"] = []string{oidctest.ClientID, "other"}
+		claims["azp"] = oidctest.ClientID
+		_, err := p.Verify(context.Background(), idp.Sign(claims), "nonce")
+		require.NoError(t, err)
+	})
+
+	invalid := map[string]func(map[string]any){
+		"expired":        func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"issued later":   func(c map[string]any) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"wrong issuer":   func(c map[string]any) { c["iss"] = "https://idp.example" },
+		"wrong audience": func(c map[string]any) { c["aud"] = "other" },
+		"no subject":     func(c map[string]any) { delete(c, "sub") },
+		"other party":    func(c map[string]any) { c["aud"] = []string{oidctest.ClientID, "other"}; c["azp"] = "other" },
+	}
+	for name, mutate := range invalid {
+		t.Run("reject token "+name, func(t *testing.T) {
+			claims := valid()
+			mutate(claims)
+			_, err := p.Verify(context.Background(), idp.Sign(claims), "nonce")
+			require.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+
+	t.Run("reject unsigned token", func(t *testing.T) {
+		parts := strings.Split(idp.Sign(valid()), ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+		_, err := p.Verify(context.Background(), parts[0]+"."+parts[1]+".", "nonce")
+		require.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+
+	t.Run("reject tampered token", func(t *testing.T) {
+		token := idp.Sign(valid())
+		other := idp.Sign(map[string]any{"iss": idp.URL, "sub": "admin"})
+		_, err := p.Verify(context.Background(), token[:len(token)-10]+other[len(other)-10:], "nonce")
+		require.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+}
+
+func TestChallenge(t *testing.T) {
+	// base64url(sha256("verifier")) without padding
+	require.Equal(t, "iMnq5o6zALKXGivsnlom_0F5_WYda32GHkxlV7mq7hQ", Challenge("verifier"))
+}
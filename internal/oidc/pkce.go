@@ -0,0 +1,19 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"ogugu/internal/secure"
+)
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() (string, error) {
+	return secure.Token(32)
+}
+
+// Challenge derives the S256 code challenge of verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
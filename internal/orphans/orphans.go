@@ -0,0 +1,46 @@
+// Package orphans decides how feeds nobody subscribes to are fetched,
+// archived and deleted.
+package orphans
+
+import (
+	"time"
+
+	"ogugu/internal/config"
+	"ogugu/internal/models"
+)
+
+type Policy struct {
+	// PollInterval is the minimum time between fetches of an orphaned
+	// feed. Zero stops fetching orphaned feeds.
+	PollInterval time.Duration
+	// ArchiveAfter is how long a feed is orphaned before it is archived
+	// and no longer fetched. Zero never archives feeds.
+	ArchiveAfter time.Duration
+	// DeleteAfter is how long a feed is orphaned before it is deleted.
+	// Zero never deletes feeds.
+	DeleteAfter time.Duration
+}
+
+func PolicyFromEnv() Policy {
+	return Policy{
+		PollInterval: config.Duration("ORPHAN_POLL_INTERVAL", 24*time.Hour),
+		ArchiveAfter: config.Duration("ORPHAN_ARCHIVE_AFTER", 30*24*time.Hour),
+		DeleteAfter:  config.Duration("ORPHAN_DELETE_AFTER", 90*24*time.Hour),
+	}
+}
+
+// ShouldFetch reports whether the worker fetches feed at now. Subscribed
+// feeds are always fetched, archived feeds never are and orphaned feeds
+// at most once per PollInterval.
+func (p Policy) ShouldFetch(feed models.RssFeed, now time.Time) bool {
+	if feed.ArchivedAt != nil {
+		return false
+	}
+	if feed.OrphanedSince == nil {
+		return true
+	}
+	if p.PollInterval <= 0 {
+		return false
+	}
+	return feed.LastFetchedAt == nil || now.Sub(*feed.LastFetchedAt) >= p.PollInterval
+}
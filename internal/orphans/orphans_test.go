@@ -0,0 +1,37 @@
+package orphans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+)
+
+func TestShouldFetch(t *testing.T) {
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	hourAgo := now.Add(-time.Hour)
+	dayAgo := now.Add(-24 * time.Hour)
+	p := Policy{PollInterval: 24 * time.Hour}
+
+	tests := []struct {
+		name string
+		feed models.RssFeed
+		want bool
+	}{
+		{"subscribed", models.RssFeed{LastFetchedAt: &hourAgo}, true},
+		{"orphaned, never fetched", models.RssFeed{OrphanedSince: &dayAgo}, true},
+		{"orphaned, fetched recently", models.RssFeed{OrphanedSince: &dayAgo, LastFetchedAt: &hourAgo}, false},
+		{"orphaned, fetched a day ago", models.RssFeed{OrphanedSince: &dayAgo, LastFetchedAt: &dayAgo}, true},
+		{"archived", models.RssFeed{OrphanedSince: &dayAgo, ArchivedAt: &hourAgo}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, p.ShouldFetch(tc.feed, now))
+		})
+	}
+
+	p.PollInterval = 0
+	require.False(t, p.ShouldFetch(models.RssFeed{OrphanedSince: &dayAgo}, now))
+}
@@ -0,0 +1,65 @@
+// Package ranking orders a user's timeline by how likely each post is to
+// interest them.
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"ogugu/internal/models"
+)
+
+// HalfLife is how long it takes a post's score to halve with age.
+const HalfLife = 24 * time.Hour
+
+// Candidates is the number of most recent posts considered for ranking.
+const Candidates = 500
+
+// Score rates a post at now. It is the product of three factors:
+//   - recency, which halves every HalfLife;
+//   - affinity, which grows with the share of the feed's posts the user
+//     read or starred, stars counting twice;
+//   - quietness, which shrinks with the feed's posting frequency so that
+//     prolific feeds don't drown quiet ones.
+func Score(now time.Time, c models.RankCandidate) float64 {
+	age := now.Sub(c.Post.PubDate)
+	if age < 0 {
+		age = 0
+	}
+	recency := math.Exp2(-float64(age) / float64(HalfLife))
+
+	affinity := 1.0
+	if c.Feed.Posts > 0 {
+		affinity += float64(c.Feed.Reads+2*c.Feed.Stars) / float64(c.Feed.Posts)
+	}
+
+	quietness := 1 / math.Sqrt(1+math.Max(c.Feed.PostsPerWeek, 0))
+
+	return recency * affinity * quietness
+}
+
+// Rank returns the candidates' posts from highest to lowest score. Posts
+// with equal scores keep the newest first.
+func Rank(now time.Time, candidates []models.RankCandidate) []models.Post {
+	scores := make([]float64, len(candidates))
+	order := make([]int, len(candidates))
+	for i, c := range candidates {
+		scores[i] = Score(now, c)
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if scores[i] != scores[j] {
+			return scores[i] > scores[j]
+		}
+		return candidates[i].Post.PubDate.After(candidates[j].Post.PubDate)
+	})
+
+	posts := make([]models.Post, len(order))
+	for n, i := range order {
+		posts[n] = candidates[i].Post
+	}
+	return posts
+}
@@ -0,0 +1,82 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+)
+
+func candidate(id string, age time.Duration, feed models.FeedSignals) models.RankCandidate {
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	return models.RankCandidate{Post: models.Post{ID: id, PubDate: now.Add(-age)}, Feed: feed}
+}
+
+func ids(posts []models.Post) []string {
+	var out []string
+	for _, p := range posts {
+		out = append(out, p.ID)
+	}
+	return out
+}
+
+func TestScore(t *testing.T) {
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	fresh := Score(now, candidate("a", 0, models.FeedSignals{}))
+	require.InDelta(t, 1.0, fresh, 1e-9)
+
+	day := Score(now, candidate("a", HalfLife, models.FeedSignals{}))
+	require.InDelta(t, 0.5, day, 1e-9)
+
+	future := Score(now, candidate("a", -time.Hour, models.FeedSignals{}))
+	require.InDelta(t, 1.0, future, 1e-9)
+
+	liked := Score(now, candidate("a", 0, models.FeedSignals{Reads: 5, Stars: 5, Posts: 10}))
+	require.InDelta(t, 2.5, liked, 1e-9)
+
+	prolific := Score(now, candidate("a", 0, models.FeedSignals{PostsPerWeek: 99}))
+	require.InDelta(t, 0.1, prolific, 1e-9)
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("newer posts first", func(t *testing.T) {
+		posts := Rank(now, []models.RankCandidate{
+			candidate("old", 48*time.Hour, models.FeedSignals{}),
+			candidate("new", time.Hour, models.FeedSignals{}),
+		})
+		require.Equal(t, []string{"new", "old"}, ids(posts))
+	})
+
+	t.Run("read feeds rise", func(t *testing.T) {
+		posts := Rank(now, []models.RankCandidate{
+			candidate("ignored", time.Hour, models.FeedSignals{Posts: 10}),
+			candidate("loved", 2*time.Hour, models.FeedSignals{Reads: 8, Stars: 4, Posts: 10}),
+		})
+		require.Equal(t, []string{"loved", "ignored"}, ids(posts))
+	})
+
+	t.Run("prolific feeds don't drown quiet ones", func(t *testing.T) {
+		busy := models.FeedSignals{PostsPerWeek: 70}
+		quiet := models.FeedSignals{PostsPerWeek: 1}
+		posts := Rank(now, []models.RankCandidate{
+			candidate("busy1", time.Hour, busy),
+			candidate("busy2", 2*time.Hour, busy),
+			candidate("busy3", 3*time.Hour, busy),
+			candidate("quiet", 6*time.Hour, quiet),
+		})
+		require.Equal(t, "quiet", posts[0].ID)
+	})
+
+	t.Run("ties keep newest first", func(t *testing.T) {
+		posts := Rank(now, []models.RankCandidate{
+			candidate("b", -2*time.Hour, models.FeedSignals{}),
+			candidate("a", -time.Hour, models.FeedSignals{}),
+		})
+		require.Equal(t, []string{"b", "a"}, ids(posts))
+	})
+}
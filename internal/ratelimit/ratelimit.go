@@ -0,0 +1,123 @@
+// Package ratelimit implements a token bucket rate limiter in redis using
+// the generic cell rate algorithm, which stores a single timestamp per key.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/config"
+)
+
+var tracer = otel.Tracer("rate limiter")
+
+// Policy allows Limit requests per Period, in bursts of up to Limit.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy reads a policy written as "limit/period", e.g. "100/1m".
+func ParsePolicy(name, raw string) (Policy, error) {
+	limit, period, ok := strings.Cut(raw, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q is not in the limit/period format", raw)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q must have a positive limit", raw)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q must have a positive period", raw)
+	}
+
+	return Policy{Name: name, Limit: n, Period: d}, nil
+}
+
+// PolicyFromEnv reads the policy from the environment variable key, falling
+// back to def when it is unset or malformed.
+func PolicyFromEnv(name, key string, def Policy) Policy {
+	def.Name = name
+	p, err := ParsePolicy(name, config.String(key, ""))
+	if err != nil {
+		return def
+	}
+	return p
+}
+
+// Result describes the state of a bucket after a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed.
+	RetryAfter time.Duration
+}
+
+// gcra keeps the theoretical arrival time of the next request in KEYS[1].
+// ARGV[1] is the interval between requests and ARGV[2] the burst size, both
+// with times in microseconds taken from the redis clock.
+var gcra = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+
+local next_tat = tat + interval
+local allowed_at = next_tat - interval * burst
+if now < allowed_at then
+	return {0, 0, tat - now, allowed_at - now}
+end
+
+redis.call("SET", KEYS[1], next_tat, "PX", math.ceil((next_tat - now) / 1000))
+return {1, math.floor((now - allowed_at) / interval), next_tat - now, 0}
+`)
+
+type Limiter struct {
+	cache *redis.Client
+}
+
+func New(cache *redis.Client) *Limiter {
+	return &Limiter{cache: cache}
+}
+
+// Allow takes a token from the bucket of key under p.
+func (l *Limiter) Allow(ctx context.Context, p Policy, key string) (Result, error) {
+	spanctx, span := tracer.Start(ctx, "rate limit")
+	defer span.End()
+
+	interval := p.Period.Microseconds() / int64(p.Limit)
+	if interval < 1 {
+		interval = 1
+	}
+
+	values, err := gcra.Run(spanctx, l.cache, []string{"ratelimit:" + p.Name + ":" + key}, interval, p.Limit).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      p.Limit,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
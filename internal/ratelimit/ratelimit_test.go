@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/database/cache/cachetest"
+)
+
+func TestLimiter(t *testing.T) {
+	rds, teardown := cachetest.SetupTestCache(t)
+	t.Cleanup(teardown)
+
+	l := New(rds)
+	p := Policy{Name: "test", Limit: 3, Period: time.Minute}
+	ctx := context.Background()
+
+	t.Run("allow a burst up to the limit", func(t *testing.T) {
+		for i := range 3 {
+			res, err := l.Allow(ctx, p, "caller")
+			require.NoError(t, err)
+			require.True(t, res.Allowed)
+			require.Equal(t, 3, res.Limit)
+			require.Equal(t, 2-i, res.Remaining)
+		}
+	})
+
+	t.Run("reject over the limit", func(t *testing.T) {
+		res, err := l.Allow(ctx, p, "caller")
+		require.NoError(t, err)
+		require.False(t, res.Allowed)
+		require.Zero(t, res.Remaining)
+		require.InDelta(t, time.Second*20, res.RetryAfter, float64(time.Second))
+		require.InDelta(t, time.Minute, res.Reset, float64(time.Second))
+	})
+
+	t.Run("buckets are per key", func(t *testing.T) {
+		res, err := l.Allow(ctx, p, "other caller")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+	})
+
+	t.Run("buckets are per policy", func(t *testing.T) {
+		res, err := l.Allow(ctx, Policy{Name: "other", Limit: 3, Period: time.Minute}, "caller")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+	})
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("feed", "10/1h")
+	require.NoError(t, err)
+	require.Equal(t, Policy{Name: "feed", Limit: 10, Period: time.Hour}, p)
+
+	for _, raw := range []string{"", "10", "0/1m", "-1/1m", "ten/1m", "10/never", "10/0s"} {
+		_, err := ParsePolicy("feed", raw)
+		require.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,157 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/models"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("api keys service")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Create stores a new api key. Only the hash of the key is persisted.
+func (r *Repository) Create(
+	ctx context.Context, id, user_id, prefix, hash string, body models.CreateAPIKeyBody,
+) (models.APIKey, error) {
+	spanctx, span := tracer.Start(ctx, "create api key")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_keys (id, user_id, name, scope, prefix, key_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, user_id, name, scope, prefix, last_used_at, created_at, updated_at;
+	`
+	var key models.APIKey
+	now := time.Now()
+	row := r.db.QueryRowContext(dbctx, query, id, user_id, body.Name, body.Scope, prefix, hash, now, now)
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Scope,
+		&key.Prefix,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *Repository) GetByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	spanctx, span := tracer.Start(ctx, "get api key by hash")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, name, scope, prefix, last_used_at, created_at, updated_at
+		FROM api_keys WHERE key_hash = $1;
+	`
+	var key models.APIKey
+	row := r.db.QueryRowContext(dbctx, query, hash)
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Scope,
+		&key.Prefix,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *Repository) ListByUser(ctx context.Context, user_id string) ([]models.APIKey, error) {
+	spanctx, span := tracer.Start(ctx, "list api keys")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, name, scope, prefix, last_used_at, created_at, updated_at
+		FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC;
+	`
+	rows, err := r.db.QueryContext(dbctx, query, user_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Scope,
+			&key.Prefix,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+			&key.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// TouchLastUsed records that a key was used at t.
+func (r *Repository) TouchLastUsed(ctx context.Context, id string, t time.Time) error {
+	spanctx, span := tracer.Start(ctx, "touch api key")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2;`
+	_, err := r.db.ExecContext(dbctx, query, t, id)
+	return err
+}
+
+func (r *Repository) Delete(ctx context.Context, user_id, id string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "delete api key")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2;`
+	res, err := r.db.ExecContext(dbctx, query, id, user_id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
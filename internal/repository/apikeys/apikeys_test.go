@@ -0,0 +1,72 @@
+package apikeys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/users"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	ks := New(db)
+	us := users.New(db)
+	userid := "userid"
+
+	_, err := us.CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	body := models.CreateAPIKeyBody{Name: "script", Scope: models.ScopeRead}
+
+	t.Run("create api key", func(t *testing.T) {
+		key, err := ks.Create(context.Background(), "keyid", userid, "ogk_abcd", "hash", body)
+		require.NoError(t, err)
+		require.Equal(t, "script", key.Name)
+		require.Nil(t, key.LastUsedAt)
+	})
+
+	t.Run("get api key by hash", func(t *testing.T) {
+		key, err := ks.GetByHash(context.Background(), "hash")
+		require.NoError(t, err)
+		require.Equal(t, userid, key.UserID)
+		require.Equal(t, models.ScopeRead, key.Scope)
+	})
+
+	t.Run("get api key by unknown hash", func(t *testing.T) {
+		_, err := ks.GetByHash(context.Background(), "unknown")
+		require.Error(t, err)
+	})
+
+	t.Run("touch last used", func(t *testing.T) {
+		err := ks.TouchLastUsed(context.Background(), "keyid", time.Now())
+		require.NoError(t, err)
+
+		keys, err := ks.ListByUser(context.Background(), userid)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.NotNil(t, keys[0].LastUsedAt)
+	})
+
+	t.Run("delete api key of another user", func(t *testing.T) {
+		n, err := ks.Delete(context.Background(), "otheruser", "keyid")
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("delete api key", func(t *testing.T) {
+		n, err := ks.Delete(context.Background(), userid, "keyid")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	})
+}
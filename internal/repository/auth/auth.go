@@ -55,3 +55,18 @@ func (r *Repository) GetPasswordWithUserID(ctx context.Context, id string) (stri
 
 	return password, nil
 }
+
+func (r *Repository) UpdatePassword(ctx context.Context, id, password string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "updating an auth entry")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE auth SET password = $1, updated_at = $2 WHERE user_id = $3;`
+	res, err := r.db.ExecContext(dbctx, query, password, time.Now(), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -23,4 +23,12 @@ func TestAuthService(t *testing.T) {
 		_, err := as.GetPasswordWithUserID(context.Background(), "dummy id")
 		require.Error(t, err)
 	})
+
+	t.Run("update password with user id", func(t *testing.T) {
+		n, err := as.UpdatePassword(context.Background(), "dummy id", "password")
+		require.NoError(t, err)
+		if n != 0 {
+			t.Error("expected no auth entry to be updated")
+		}
+	})
 }
@@ -0,0 +1,136 @@
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/models"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("digests service")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+const columns = `user_id, frequency, hour, weekday, timezone, unsubscribe_token, last_sent_at, updated_at`
+
+func (r *Repository) Get(ctx context.Context, user_id string) (models.DigestSettings, error) {
+	spanctx, span := tracer.Start(ctx, "get digest settings")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT ` + columns + ` FROM digest_settings WHERE user_id = $1;`
+	return scan(r.db.QueryRowContext(dbctx, query, user_id))
+}
+
+// Upsert stores a user's digest settings. The unsubscribe token is only
+// used when the user has no settings yet, and a missing weekday is left
+// unchanged.
+func (r *Repository) Upsert(
+	ctx context.Context, user_id, token string, body models.UpdateDigestBody,
+) (models.DigestSettings, error) {
+	spanctx, span := tracer.Start(ctx, "upsert digest settings")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO digest_settings (user_id, frequency, hour, weekday, timezone, unsubscribe_token, created_at, updated_at)
+		VALUES ($1, $2, $3, COALESCE($4, 1), $5, $6, $7, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET frequency = EXCLUDED.frequency, hour = EXCLUDED.hour,
+		weekday = COALESCE($4, digest_settings.weekday), timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at
+		RETURNING ` + columns + `;
+	`
+	row := r.db.QueryRowContext(
+		dbctx, query, user_id, body.Frequency, body.Hour, body.Weekday, body.Timezone, token, time.Now(),
+	)
+	return scan(row)
+}
+
+// ListEnabled returns the settings of every user who opted in to digests.
+func (r *Repository) ListEnabled(ctx context.Context) ([]models.DigestSettings, error) {
+	spanctx, span := tracer.Start(ctx, "list enabled digests")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT ` + columns + ` FROM digest_settings WHERE frequency <> 'off';`
+	rows, err := r.db.QueryContext(dbctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []models.DigestSettings
+	for rows.Next() {
+		s, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (r *Repository) MarkSent(ctx context.Context, user_id string, t time.Time) error {
+	spanctx, span := tracer.Start(ctx, "mark digest sent")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE digest_settings SET last_sent_at = $1 WHERE user_id = $2;`
+	_, err := r.db.ExecContext(dbctx, query, t, user_id)
+	return err
+}
+
+// Unsubscribe turns off the digests of the user with the given token.
+func (r *Repository) Unsubscribe(ctx context.Context, token string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "unsubscribe from digests")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE digest_settings SET frequency = 'off', updated_at = $1 WHERE unsubscribe_token = $2;`
+	res, err := r.db.ExecContext(dbctx, query, time.Now(), token)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scan(row interface{ Scan(...any) error }) (models.DigestSettings, error) {
+	var s models.DigestSettings
+	err := row.Scan(
+		&s.UserID,
+		&s.Frequency,
+		&s.Hour,
+		&s.Weekday,
+		&s.Timezone,
+		&s.UnsubscribeToken,
+		&s.LastSentAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return models.DigestSettings{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,80 @@
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/users"
+)
+
+func TestDigestService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	ds := New(db)
+	userid := "userid"
+
+	_, err := users.New(db).CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	hour, weekday := 7, 5
+
+	t.Run("no settings", func(t *testing.T) {
+		_, err := ds.Get(context.Background(), userid)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("opt in", func(t *testing.T) {
+		body := models.UpdateDigestBody{Frequency: "weekly", Hour: &hour, Weekday: &weekday, Timezone: "Africa/Lagos"}
+		s, err := ds.Upsert(context.Background(), userid, "token1", body)
+		require.NoError(t, err)
+		require.Equal(t, "weekly", s.Frequency)
+		require.Equal(t, 5, s.Weekday)
+		require.Equal(t, "token1", s.UnsubscribeToken)
+
+		enabled, err := ds.ListEnabled(context.Background())
+		require.NoError(t, err)
+		require.Len(t, enabled, 1)
+	})
+
+	t.Run("update keeps token and weekday", func(t *testing.T) {
+		body := models.UpdateDigestBody{Frequency: "daily", Hour: &hour, Timezone: "UTC"}
+		s, err := ds.Upsert(context.Background(), userid, "token2", body)
+		require.NoError(t, err)
+		require.Equal(t, "daily", s.Frequency)
+		require.Equal(t, 5, s.Weekday)
+		require.Equal(t, "token1", s.UnsubscribeToken)
+	})
+
+	t.Run("mark sent", func(t *testing.T) {
+		err := ds.MarkSent(context.Background(), userid, time.Now())
+		require.NoError(t, err)
+		s, err := ds.Get(context.Background(), userid)
+		require.NoError(t, err)
+		require.NotNil(t, s.LastSentAt)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		n, err := ds.Unsubscribe(context.Background(), "wrong")
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		n, err = ds.Unsubscribe(context.Background(), "token1")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		enabled, err := ds.ListEnabled(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, enabled)
+	})
+}
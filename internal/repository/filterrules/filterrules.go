@@ -0,0 +1,126 @@
+package filterrules
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/models"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("filter rules service")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(
+	ctx context.Context, id, user_id string, body models.CreateFilterRuleBody,
+) (models.FilterRule, error) {
+	spanctx, span := tracer.Start(ctx, "create filter rule")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO filter_rules (id, user_id, field, match, pattern, action, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, user_id, field, match, pattern, action, created_at;
+	`
+	row := r.db.QueryRowContext(
+		dbctx, query, id, user_id, body.Field, body.Match, body.Pattern, body.Action, time.Now(),
+	)
+	return scan(row)
+}
+
+func (r *Repository) ListByUser(ctx context.Context, user_id string) ([]models.FilterRule, error) {
+	spanctx, span := tracer.Start(ctx, "list filter rules")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, field, match, pattern, action, created_at
+		FROM filter_rules WHERE user_id = $1 ORDER BY created_at;
+	`
+	return r.list(dbctx, query, user_id)
+}
+
+// ListBySubscribers returns the rules of every user subscribed to a feed.
+func (r *Repository) ListBySubscribers(ctx context.Context, rss_id string) ([]models.FilterRule, error) {
+	spanctx, span := tracer.Start(ctx, "list filter rules of feed subscribers")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		SELECT fr.id, fr.user_id, fr.field, fr.match, fr.pattern, fr.action, fr.created_at
+		FROM filter_rules fr
+		INNER JOIN subscriptions sub ON sub.user_id = fr.user_id
+		WHERE sub.rss_id = $1 ORDER BY fr.user_id, fr.created_at;
+	`
+	return r.list(dbctx, query, rss_id)
+}
+
+func (r *Repository) Delete(ctx context.Context, user_id, id string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "delete filter rule")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `DELETE FROM filter_rules WHERE id = $1 AND user_id = $2;`
+	res, err := r.db.ExecContext(dbctx, query, id, user_id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *Repository) list(ctx context.Context, query string, args ...any) ([]models.FilterRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.FilterRule{}
+	for rows.Next() {
+		rule, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func scan(row interface{ Scan(...any) error }) (models.FilterRule, error) {
+	var rule models.FilterRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.Field,
+		&rule.Match,
+		&rule.Pattern,
+		&rule.Action,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		return models.FilterRule{}, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,74 @@
+package filterrules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/rss"
+	"ogugu/internal/repository/subscriptions"
+	"ogugu/internal/repository/users"
+)
+
+func TestFilterRuleService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	fs := New(db)
+	userid := "userid"
+	rssid := "rssid"
+
+	_, err := users.New(db).CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	var meta models.RSSMeta
+	meta.Channel.Title = "Example RSS Feed"
+	_, err = rss.New(db).Create(context.Background(), rssid, "rsslink", meta)
+	require.NoError(t, err)
+
+	body := models.CreateFilterRuleBody{Field: "title", Match: "keyword", Pattern: "sponsored", Action: "hide"}
+
+	t.Run("create filter rule", func(t *testing.T) {
+		rule, err := fs.Create(context.Background(), "ruleid", userid, body)
+		require.NoError(t, err)
+		require.Equal(t, "sponsored", rule.Pattern)
+	})
+
+	t.Run("list filter rules", func(t *testing.T) {
+		rules, err := fs.ListByUser(context.Background(), userid)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+	})
+
+	t.Run("list rules of subscribers", func(t *testing.T) {
+		rules, err := fs.ListBySubscribers(context.Background(), rssid)
+		require.NoError(t, err)
+		require.Empty(t, rules)
+
+		_, err = subscriptions.New(db).CreateSub(context.Background(), "subid", userid, rssid)
+		require.NoError(t, err)
+		rules, err = fs.ListBySubscribers(context.Background(), rssid)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, userid, rules[0].UserID)
+	})
+
+	t.Run("delete filter rule of another user", func(t *testing.T) {
+		n, err := fs.Delete(context.Background(), "someoneelse", "ruleid")
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("delete filter rule", func(t *testing.T) {
+		n, err := fs.Delete(context.Background(), userid, "ruleid")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	})
+}
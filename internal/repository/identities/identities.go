@@ -0,0 +1,59 @@
+package identities
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("identities service")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Create links the account issuer/subject at an external identity provider
+// to a user. Linking an identity that is already linked is a no-op.
+func (r *Repository) Create(ctx context.Context, id, user_id, issuer, subject, email string) error {
+	spanctx, span := tracer.Start(ctx, "create identity")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO identities (id, user_id, issuer, subject, email, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (issuer, subject) DO NOTHING;
+	`
+	now := time.Now()
+	_, err := r.db.ExecContext(dbctx, query, id, user_id, issuer, subject, email, now, now)
+	return err
+}
+
+// GetUserID returns the id of the user linked to issuer/subject.
+func (r *Repository) GetUserID(ctx context.Context, issuer, subject string) (string, error) {
+	spanctx, span := tracer.Start(ctx, "get identity user")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT user_id FROM identities WHERE issuer = $1 AND subject = $2;`
+	var userID string
+	if err := r.db.QueryRowContext(dbctx, query, issuer, subject).Scan(&userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
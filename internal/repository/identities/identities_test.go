@@ -0,0 +1,49 @@
+package identities
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/users"
+)
+
+func TestIdentityService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	is := New(db)
+	us := users.New(db)
+	userid := "userid"
+	issuer := "https://idp.ogugu.test"
+
+	_, err := us.CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+	})
+	require.NoError(t, err)
+
+	t.Run("create identity", func(t *testing.T) {
+		err := is.Create(context.Background(), "id1", userid, issuer, "subject", "user@ogugu.test")
+		require.NoError(t, err)
+	})
+
+	t.Run("create identity twice", func(t *testing.T) {
+		err := is.Create(context.Background(), "id2", userid, issuer, "subject", "user@ogugu.test")
+		require.NoError(t, err)
+	})
+
+	t.Run("get user id", func(t *testing.T) {
+		id, err := is.GetUserID(context.Background(), issuer, "subject")
+		require.NoError(t, err)
+		require.Equal(t, userid, id)
+	})
+
+	t.Run("get user id from another issuer", func(t *testing.T) {
+		_, err := is.GetUserID(context.Background(), "https://other.ogugu.test", "subject")
+		require.Error(t, err)
+	})
+}
@@ -3,16 +3,25 @@ package posts
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"ogugu/internal/models"
+	"ogugu/internal/simhash"
+	"ogugu/internal/urlnorm"
 )
 
 var tracer = otel.Tracer("posts service")
 
 const dbtimeout = time.Second * 3
 
+// prunetimeout bounds Prune and BackfillCanonicalURLs, which may touch many
+// posts at once.
+const prunetimeout = time.Minute
+
 type Repository struct {
 	db *sql.DB
 }
@@ -21,30 +30,50 @@ func New(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) CreatePost(
-	ctx context.Context, id string, rss_id string, p models.CreatePost,
-) (models.Post, error) {
-	spanctx, span := tracer.Start(ctx, "creating a new post")
-	defer span.End()
-
-	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
-	defer cancel()
-
-	query := `
-		INSERT INTO posts (id, rss_id, title, description, link, pubdate, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, title, description, link, pubdate, created_at, updated_at;
-	`
-	row := r.db.QueryRowContext(
-		dbctx, query, id, rss_id, p.Title, p.Description, p.Link, p.PubDate, time.Now(), time.Now(),
-	)
+// Columns selects a post from a query over the posts table, aggregating
+// its enclosures, authors and categories into json arrays. Rows are read
+// with Scan.
+const Columns = `
+	posts.id, posts.rss_id, COALESCE((SELECT title FROM rss WHERE rss.id = posts.rss_id), ''),
+	COALESCE(posts.cluster_id, posts.id), posts.title, posts.description, posts.content, posts.full_content, posts.summary, posts.link,
+	posts.thumbnail, posts.episode,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'url', e.url, 'mime_type', e.mime_type, 'length', e.length, 'duration', e.duration
+		) ORDER BY e.position)
+		FROM enclosures e WHERE e.post_id = posts.id
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(a.name ORDER BY pa.position)
+		FROM post_authors pa INNER JOIN authors a ON a.slug = pa.author_slug
+		WHERE pa.post_id = posts.id
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(c.name ORDER BY pc.position)
+		FROM post_categories pc INNER JOIN categories c ON c.slug = pc.category_slug
+		WHERE pc.post_id = posts.id
+	), '[]'),
+	posts.pubdate, posts.created_at, posts.updated_at`
 
+// Scan reads a row selected with Columns.
+func Scan(row interface{ Scan(...any) error }) (models.Post, error) {
 	var post models.Post
 	err := row.Scan(
 		&post.ID,
+		&post.RssID,
+		&post.FeedTitle,
+		&post.ClusterID,
 		&post.Title,
 		&post.Description,
+		&post.Content,
+		&post.FullContent,
+		&post.Summary,
 		&post.Link,
+		&post.Thumbnail,
+		&post.Episode,
+		&post.Enclosures,
+		&post.Authors,
+		&post.Categories,
 		&post.PubDate,
 		&post.CreatedAt,
 		&post.UpdatedAt,
@@ -52,28 +81,86 @@ func (r *Repository) CreatePost(
 	if err != nil {
 		return models.Post{}, err
 	}
-
 	return post, nil
 }
 
-func (r *Repository) GetByID(ctx context.Context, id string) (models.Post, error) {
-	spanctx, span := tracer.Start(ctx, "get a post by id")
+// Filter returns a condition that matches posts with the filter's author
+// and category, using placeholders $n and $n+1 for the values returned by
+// FilterArgs. Empty filter values match every post.
+func Filter(n int) string {
+	return fmt.Sprintf(`
+		($%d = '' OR EXISTS (
+			SELECT 1 FROM post_authors WHERE post_id = posts.id AND author_slug = $%d
+		))
+		AND ($%d = '' OR EXISTS (
+			SELECT 1 FROM post_categories WHERE post_id = posts.id AND category_slug = $%d
+		))`, n, n, n+1, n+1)
+}
+
+func FilterArgs(f models.PostFilter) []any {
+	return []any{Slug(f.Author), Slug(f.Category)}
+}
+
+// Slug is the key authors and categories are stored and filtered by, so
+// "Go Lang" and "go  lang" are the same category.
+func Slug(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// CreatePost returns sql.ErrNoRows when the feed already has a post with
+// the same link. Other feeds' posts with the link are clustered with it.
+func (r *Repository) CreatePost(
+	ctx context.Context, id string, rss_id string, p models.CreatePost,
+) (models.Post, error) {
+	spanctx, span := tracer.Start(ctx, "creating a new post")
 	defer span.End()
 
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return models.Post{}, err
+	}
+	defer tx.Rollback()
+
+	cluster_id, err := cluster(dbctx, tx, rss_id, p)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if cluster_id == "" {
+		cluster_id = id
+	}
+	fingerprint := signed(p.SimHash)
+
 	query := `
-		SELECT id, title, description, link, pubdate, created_at, updated_at 
-		FROM posts WHERE id = $1;
+		INSERT INTO posts (
+			id, rss_id, title, description, content, summary, link, thumbnail, episode, pubdate,
+			canonical_url, simhash, cluster_id, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (rss_id, link) DO NOTHING
+		RETURNING id, rss_id, cluster_id, title, description, content, full_content, summary, link, thumbnail, episode, pubdate, created_at, updated_at;
 	`
-	row := r.db.QueryRowContext(dbctx, query, id)
+	row := tx.QueryRowContext(
+		dbctx, query, id, rss_id, p.Title, p.Description, p.Content, p.Summary, p.Link, p.Thumbnail, p.Episode,
+		p.PubDate, sql.NullString{String: p.CanonicalURL, Valid: p.CanonicalURL != ""}, fingerprint, cluster_id,
+		time.Now(), time.Now(),
+	)
+
 	var post models.Post
-	err := row.Scan(
+	err = row.Scan(
 		&post.ID,
+		&post.RssID,
+		&post.ClusterID,
 		&post.Title,
 		&post.Description,
+		&post.Content,
+		&post.FullContent,
+		&post.Summary,
 		&post.Link,
+		&post.Thumbnail,
+		&post.Episode,
 		&post.PubDate,
 		&post.CreatedAt,
 		&post.UpdatedAt,
@@ -82,20 +169,50 @@ func (r *Repository) GetByID(ctx context.Context, id string) (models.Post, error
 		return models.Post{}, err
 	}
 
-	return post, nil
+	query = `
+		INSERT INTO enclosures (post_id, position, url, mime_type, length, duration)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	for i, e := range p.Enclosures {
+		_, err := tx.ExecContext(dbctx, query, post.ID, i, e.URL, e.MimeType, e.Length, e.Duration)
+		if err != nil {
+			return models.Post{}, err
+		}
+	}
+	post.Enclosures = append(models.Enclosures{}, p.Enclosures...)
+
+	post.Authors, err = link(dbctx, tx, "authors", "post_authors", "author_slug", post.ID, p.Authors)
+	if err != nil {
+		return models.Post{}, err
+	}
+	post.Categories, err = link(dbctx, tx, "categories", "post_categories", "category_slug", post.ID, p.Categories)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	return post, tx.Commit()
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (models.Post, error) {
+	spanctx, span := tracer.Start(ctx, "get a post by id")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT ` + Columns + ` FROM posts WHERE id = $1;`
+	return Scan(r.db.QueryRowContext(dbctx, query, id))
 }
 
-func (r *Repository) Fetch(ctx context.Context) ([]models.Post, error) {
+func (r *Repository) Fetch(ctx context.Context, filter models.PostFilter) ([]models.Post, error) {
 	spanctx, span := tracer.Start(ctx, "fetch all posts")
 	defer span.End()
 
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `
-		SELECT id, title, description, link, pubdate, created_at, updated_at FROM posts;
-	`
-	rows, err := r.db.QueryContext(dbctx, query)
+	query := `SELECT ` + Columns + ` FROM posts WHERE ` + Filter(1) + `;`
+	rows, err := r.db.QueryContext(dbctx, query, FilterArgs(filter)...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,23 +220,224 @@ func (r *Repository) Fetch(ctx context.Context) ([]models.Post, error) {
 
 	var posts []models.Post
 	for rows.Next() {
-		var post models.Post
-		err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Description,
-			&post.Link,
-			&post.PubDate,
-			&post.CreatedAt,
-			&post.UpdatedAt,
-		)
+		post, err := Scan(rows)
 		if err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
 	}
 
-	return posts, nil
+	return posts, rows.Err()
+}
+
+// clusterWindow is how far apart in time two posts may be published and
+// still be taken for the same story.
+const clusterWindow = "3 days"
+
+// cluster returns the cluster of the earliest post published around p with
+// the same canonical url or a simhash within simhash.Threshold, or "" when
+// p is a new story. Clusters that have a post of rss_id are skipped, so
+// posts of one feed, like templated series posts, are never grouped.
+func cluster(ctx context.Context, tx *sql.Tx, rss_id string, p models.CreatePost) (string, error) {
+	if p.CanonicalURL == "" && p.SimHash == nil {
+		return "", nil
+	}
+	fingerprint := signed(p.SimHash)
+
+	query := `
+		SELECT COALESCE(cluster_id, id) FROM posts
+		WHERE pubdate BETWEEN $3::timestamp - interval '` + clusterWindow + `' AND $3::timestamp + interval '` + clusterWindow + `'
+		AND rss_id <> $5
+		AND NOT EXISTS (
+			SELECT 1 FROM posts m
+			WHERE COALESCE(m.cluster_id, m.id) = COALESCE(posts.cluster_id, posts.id) AND m.rss_id = $5
+		)
+		AND (
+			canonical_url = $1
+			OR ($2::bigint IS NOT NULL AND bit_count((simhash # $2::bigint)::bit(64)) <= $4)
+		)
+		ORDER BY created_at
+		LIMIT 1;
+	`
+	var cluster_id string
+	err := tx.QueryRowContext(ctx, query, p.CanonicalURL, fingerprint, p.PubDate, simhash.Threshold, rss_id).Scan(&cluster_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return cluster_id, err
+}
+
+// signed stores a simhash in a BIGINT column, which holds the same bits.
+func signed(h *uint64) *int64 {
+	if h == nil {
+		return nil
+	}
+	v := int64(*h)
+	return &v
+}
+
+// SetCanonical stores the canonical url found on a post's page. A post that
+// no other post was grouped with joins the cluster of an earlier post with
+// the same canonical url.
+func (r *Repository) SetCanonical(ctx context.Context, id, canonical_url string) error {
+	spanctx, span := tracer.Start(ctx, "set post canonical url")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE posts SET canonical_url = $1, updated_at = $3,
+		cluster_id = CASE
+			WHEN EXISTS (SELECT 1 FROM posts p WHERE p.cluster_id = $2 AND p.id <> $2) THEN posts.cluster_id
+			ELSE COALESCE((
+				SELECT COALESCE(p.cluster_id, p.id) FROM posts p
+				WHERE p.canonical_url = $1 AND p.id <> $2
+				AND NOT EXISTS (
+					SELECT 1 FROM posts m
+					WHERE COALESCE(m.cluster_id, m.id) = COALESCE(p.cluster_id, p.id) AND m.rss_id = posts.rss_id
+				)
+				ORDER BY p.created_at LIMIT 1
+			), posts.cluster_id)
+		END
+		WHERE id = $2;
+	`
+	_, err := r.db.ExecContext(dbctx, query, canonical_url, id, time.Now())
+	return err
+}
+
+// backfillBatch is how many posts BackfillCanonicalURLs reads at a time.
+const backfillBatch = 1000
+
+// BackfillCanonicalURLs sets the canonical url of posts created before
+// clustering, so new copies of those stories are grouped with them. It
+// returns how many posts were updated.
+func (r *Repository) BackfillCanonicalURLs(ctx context.Context) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "backfill post canonical urls")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, prunetimeout)
+	defer cancel()
+
+	type post struct{ id, link string }
+	var updated int64
+	for {
+		query := `SELECT id, link FROM posts WHERE canonical_url IS NULL ORDER BY id LIMIT $1;`
+		rows, err := r.db.QueryContext(dbctx, query, backfillBatch)
+		if err != nil {
+			return updated, err
+		}
+		var batch []post
+		for rows.Next() {
+			var p post
+			if err := rows.Scan(&p.id, &p.link); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+
+		query = `UPDATE posts SET canonical_url = $1 WHERE id = $2;`
+		for _, p := range batch {
+			if _, err := r.db.ExecContext(dbctx, query, urlnorm.Canonical(p.link), p.id); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		if len(batch) < backfillBatch {
+			return updated, nil
+		}
+	}
+}
+
+// Collapse keeps the first post of every cluster in a timeline and lists
+// the posts of the same cluster from other feeds in its AlsoIn. A post from
+// a feed the cluster already shows is a different post of that feed and is
+// kept in the timeline.
+func Collapse(timeline []models.Post) []models.Post {
+	collapsed := []models.Post{}
+	first := map[string]int{}
+	for _, post := range timeline {
+		key := post.ClusterID
+		if key == "" {
+			key = post.ID
+		}
+		i, ok := first[key]
+		if !ok {
+			first[key] = len(collapsed)
+			collapsed = append(collapsed, post)
+			continue
+		}
+
+		primary := &collapsed[i]
+		seen := post.RssID == primary.RssID
+		for _, also := range primary.AlsoIn {
+			seen = seen || also.RssID == post.RssID
+		}
+		if seen {
+			collapsed = append(collapsed, post)
+			continue
+		}
+		primary.AlsoIn = append(primary.AlsoIn, models.AlsoIn{
+			PostID:    post.ID,
+			RssID:     post.RssID,
+			FeedTitle: post.FeedTitle,
+			Link:      post.Link,
+		})
+	}
+	return collapsed
+}
+
+// SetFullContent stores the article extracted from a post's page.
+func (r *Repository) SetFullContent(ctx context.Context, id, content string) error {
+	spanctx, span := tracer.Start(ctx, "set post full content")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE posts SET full_content = $1, updated_at = $2 WHERE id = $3;`
+	_, err := r.db.ExecContext(dbctx, query, content, time.Now(), id)
+	return err
+}
+
+// SetState marks a post read or unread and starred or unstarred for a user.
+// A nil mark is left unchanged. Marking a post read again keeps the time it
+// was first read.
+func (r *Repository) SetState(
+	ctx context.Context, user_id, post_id string, read, starred *bool,
+) (models.PostState, error) {
+	spanctx, span := tracer.Start(ctx, "set post state")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO post_states (user_id, post_id, read_at, starred_at, updated_at)
+		VALUES ($1, $2, CASE WHEN $3::boolean THEN $5::timestamp END, CASE WHEN $4::boolean THEN $5::timestamp END, $5)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET
+			read_at = CASE
+				WHEN $3::boolean IS NULL THEN post_states.read_at
+				WHEN $3::boolean THEN COALESCE(post_states.read_at, $5)
+			END,
+			starred_at = CASE
+				WHEN $4::boolean IS NULL THEN post_states.starred_at
+				WHEN $4::boolean THEN COALESCE(post_states.starred_at, $5)
+			END,
+			updated_at = $5
+		RETURNING post_id, read_at, starred_at, updated_at;
+	`
+	var state models.PostState
+	row := r.db.QueryRowContext(dbctx, query, user_id, post_id, read, starred, time.Now())
+	if err := row.Scan(&state.PostID, &state.ReadAt, &state.StarredAt, &state.UpdatedAt); err != nil {
+		return models.PostState{}, err
+	}
+	return state, nil
 }
 
 func (ps *Repository) DeletePost(ctx context.Context, id string) (int64, error) {
@@ -136,3 +454,84 @@ func (ps *Repository) DeletePost(ctx context.Context, id string) (int64, error)
 	}
 	return r.RowsAffected()
 }
+
+// Prune deletes the posts that policy, or their feed's override, no longer
+// keeps at now, and reports how many were deleted per feed. Starred posts
+// are never pruned. With dryRun the posts are only counted.
+func (r *Repository) Prune(
+	ctx context.Context, policy models.RetentionPolicy, now time.Time, dryRun bool,
+) ([]models.PruneReport, error) {
+	spanctx, span := tracer.Start(ctx, "prune posts")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, prunetimeout)
+	defer cancel()
+
+	pruned := `DELETE FROM posts WHERE id IN (SELECT id FROM candidates) RETURNING rss_id`
+	if dryRun {
+		pruned = `SELECT rss_id FROM candidates`
+	}
+	query := `
+		WITH ranked AS (
+			SELECT posts.id, posts.rss_id, posts.pubdate,
+			row_number() OVER (PARTITION BY posts.rss_id ORDER BY posts.pubdate DESC, posts.id) AS n,
+			COALESCE(rss.retention_days::bigint * 86400, $1) AS max_age,
+			COALESCE(rss.retention_max_items, $2) AS max_items
+			FROM posts INNER JOIN rss ON rss.id = posts.rss_id
+		), candidates AS (
+			SELECT id, rss_id FROM ranked
+			WHERE (
+				(max_age > 0 AND pubdate < $3::timestamp - max_age * interval '1 second')
+				OR (max_items > 0 AND n > max_items)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM post_states ps WHERE ps.post_id = ranked.id AND ps.starred_at IS NOT NULL
+			)
+		), pruned AS (` + pruned + `)
+		SELECT rss.id, rss.title, count(*) FROM pruned
+		INNER JOIN rss ON rss.id = pruned.rss_id
+		GROUP BY rss.id, rss.title
+		ORDER BY count(*) DESC, rss.id;
+	`
+	rows, err := r.db.QueryContext(dbctx, query, int64(policy.MaxAge.Seconds()), policy.MaxItems, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.PruneReport
+	for rows.Next() {
+		var report models.PruneReport
+		if err := rows.Scan(&report.RssID, &report.Title, &report.Posts); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// link stores names in a lookup table keyed by slug and links them to a
+// post in order. It returns the names that were linked.
+func link(ctx context.Context, tx *sql.Tx, table, join, column, post_id string, names []string) ([]string, error) {
+	linked := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		slug := Slug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		query := fmt.Sprintf(`INSERT INTO %s (slug, name) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING;`, table)
+		if _, err := tx.ExecContext(ctx, query, slug, strings.TrimSpace(name)); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`INSERT INTO %s (post_id, %s, position) VALUES ($1, $2, $3);`, join, column)
+		if _, err := tx.ExecContext(ctx, query, post_id, slug, len(linked)); err != nil {
+			return nil, err
+		}
+		linked = append(linked, name)
+	}
+	return linked, nil
+}
@@ -2,6 +2,7 @@ package posts
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -9,8 +10,33 @@ import (
 	"ogugu/internal/models"
 	"ogugu/internal/repository"
 	"ogugu/internal/repository/rss"
+	"ogugu/internal/repository/users"
 )
 
+func TestSlug(t *testing.T) {
+	require.Equal(t, "go-lang", Slug("  Go   Lang "))
+	require.Equal(t, "", Slug("   "))
+}
+
+func TestCollapse(t *testing.T) {
+	timeline := Collapse([]models.Post{
+		{ID: "a", RssID: "feed1", ClusterID: "a"},
+		{ID: "b", RssID: "feed2", FeedTitle: "Feed 2", ClusterID: "a", Link: "https://b.example"},
+		{ID: "c", RssID: "feed3"},
+		{ID: "d", RssID: "feed2", ClusterID: "a"},
+		{ID: "e", RssID: "feed1", ClusterID: "a"},
+	})
+	require.Len(t, timeline, 4)
+	require.Equal(t, "a", timeline[0].ID)
+	require.Equal(t, []models.AlsoIn{
+		{PostID: "b", RssID: "feed2", FeedTitle: "Feed 2", Link: "https://b.example"},
+	}, timeline[0].AlsoIn)
+	// other posts of feeds the cluster already shows are kept
+	require.Equal(t, "c", timeline[1].ID)
+	require.Equal(t, "d", timeline[2].ID)
+	require.Equal(t, "e", timeline[3].ID)
+}
+
 func TestPostService(t *testing.T) {
 	db, teardown := repository.SetupTestDB(t)
 	t.Cleanup(teardown)
@@ -37,13 +63,264 @@ func TestPostService(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("create post with an existing link", func(t *testing.T) {
+		p := models.CreatePost{Title: "again", Description: "actually", Link: "www.whocares.com", PubDate: time.Now().Format(time.RFC1123)}
+		_, err := ps.CreatePost(context.Background(), "other", rss_id, p)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
 	t.Run("get post by id", func(t *testing.T) {
-		_, err := ps.GetByID(context.Background(), id)
+		post, err := ps.GetByID(context.Background(), id)
+		require.NoError(t, err)
+		require.Empty(t, post.Enclosures)
+		require.Nil(t, post.Episode)
+	})
+
+	t.Run("podcast episode stored", func(t *testing.T) {
+		p := models.CreatePost{
+			Title: "episode", Link: "www.whocares.com/episode", PubDate: time.Now().Format(time.RFC1123),
+			Thumbnail: "https://cdn.example/thumb.jpg",
+			Episode:   &models.Episode{Duration: 3723, Number: 2, Season: 1, Type: "full", Explicit: true},
+			Enclosures: []models.Enclosure{
+				{URL: "https://cdn.example/ep2.mp3", MimeType: "audio/mpeg", Length: 12345678, Duration: 3723},
+				{URL: "https://cdn.example/ep2.mp4", MimeType: "video/mp4"},
+			},
+		}
+		created, err := ps.CreatePost(context.Background(), "episode", rss_id, p)
+		require.NoError(t, err)
+		require.Len(t, created.Enclosures, 2)
+
+		post, err := ps.GetByID(context.Background(), "episode")
+		require.NoError(t, err)
+		require.Equal(t, "https://cdn.example/thumb.jpg", post.Thumbnail)
+		require.Equal(t, p.Episode, post.Episode)
+		require.Equal(t, models.Enclosures(p.Enclosures), post.Enclosures)
+
+		_, err = ps.DeletePost(context.Background(), "episode")
+		require.NoError(t, err)
+	})
+
+	t.Run("content and summary stored", func(t *testing.T) {
+		p := models.CreatePost{
+			Title: "full", Description: "<p>short</p>", Content: "<p>long body</p>", Summary: "short",
+			Link: "www.whocares.com/full", PubDate: time.Now().Format(time.RFC1123),
+		}
+		_, err := ps.CreatePost(context.Background(), "full", rss_id, p)
+		require.NoError(t, err)
+
+		post, err := ps.GetByID(context.Background(), "full")
+		require.NoError(t, err)
+		require.Equal(t, "<p>long body</p>", post.Content)
+		require.Equal(t, "short", post.Summary)
+		require.Empty(t, post.FullContent)
+
+		err = ps.SetFullContent(context.Background(), "full", "<p>the whole article</p>")
+		require.NoError(t, err)
+		post, err = ps.GetByID(context.Background(), "full")
+		require.NoError(t, err)
+		require.Equal(t, "<p>the whole article</p>", post.FullContent)
+
+		_, err = ps.DeletePost(context.Background(), "full")
+		require.NoError(t, err)
+	})
+
+	t.Run("filter by author and category", func(t *testing.T) {
+		p := models.CreatePost{
+			Title: "tagged", Link: "www.whocares.com/tagged", PubDate: time.Now().Format(time.RFC1123),
+			Authors:    []string{"Jane Doe", "jane  doe"},
+			Categories: []string{"Golang", "Feeds"},
+		}
+		created, err := ps.CreatePost(context.Background(), "tagged", rss_id, p)
+		require.NoError(t, err)
+		require.Equal(t, models.Names{"Jane Doe"}, created.Authors)
+
+		post, err := ps.GetByID(context.Background(), "tagged")
+		require.NoError(t, err)
+		require.Equal(t, models.Names{"Jane Doe"}, post.Authors)
+		require.Equal(t, models.Names{"Golang", "Feeds"}, post.Categories)
+
+		found, err := ps.Fetch(context.Background(), models.PostFilter{Category: "golang", Author: "JANE DOE"})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, "tagged", found[0].ID)
+
+		found, err = ps.Fetch(context.Background(), models.PostFilter{Category: "rust"})
+		require.NoError(t, err)
+		require.Empty(t, found)
+
+		_, err = ps.DeletePost(context.Background(), "tagged")
+		require.NoError(t, err)
+	})
+
+	t.Run("read and star a post", func(t *testing.T) {
+		var user models.CreateUserBody
+		user.Username = "reader"
+		user.Password = "password"
+		user.Email = "reader@example.com"
+		_, err := users.New(db).CreateUser(context.Background(), "reader", user)
+		require.NoError(t, err)
+
+		yes, no := true, false
+		state, err := ps.SetState(context.Background(), "reader", id, &yes, nil)
+		require.NoError(t, err)
+		require.NotNil(t, state.ReadAt)
+		require.Nil(t, state.StarredAt)
+		readAt := *state.ReadAt
+
+		state, err = ps.SetState(context.Background(), "reader", id, &yes, &yes)
+		require.NoError(t, err)
+		require.True(t, readAt.Equal(*state.ReadAt))
+		require.NotNil(t, state.StarredAt)
+
+		state, err = ps.SetState(context.Background(), "reader", id, &no, nil)
+		require.NoError(t, err)
+		require.Nil(t, state.ReadAt)
+		require.NotNil(t, state.StarredAt)
+	})
+
+	t.Run("duplicates are clustered", func(t *testing.T) {
+		var meta models.RSSMeta
+		meta.Channel.Title = "Aggregator"
+		_, err := rs.Create(context.Background(), "aggregator", "aggregator-link", meta)
+		require.NoError(t, err)
+
+		published := time.Now().Format(time.RFC3339)
+		original, err := ps.CreatePost(context.Background(), "original", rss_id, models.CreatePost{
+			Title: "story", Link: "https://a.example/story", CanonicalURL: "https://a.example/story", PubDate: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "original", original.ClusterID)
+
+		copied, err := ps.CreatePost(context.Background(), "copy", "aggregator", models.CreatePost{
+			Title: "story", Link: "https://a.example/story?utm_source=agg", CanonicalURL: "https://a.example/story",
+			PubDate: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "original", copied.ClusterID)
+
+		_, err = db.Exec(`UPDATE posts SET canonical_url = NULL WHERE id = 'original';`)
+		require.NoError(t, err)
+		n, err := ps.BackfillCanonicalURLs(context.Background())
+		require.NoError(t, err)
+		require.NotZero(t, n)
+		var canonical string
+		err = db.QueryRow(`SELECT canonical_url FROM posts WHERE id = 'original';`).Scan(&canonical)
+		require.NoError(t, err)
+		require.Equal(t, "https://a.example/story", canonical)
+
+		meta.Channel.Title = "Mirror"
+		_, err = rs.Create(context.Background(), "mirror", "mirror-link", meta)
+		require.NoError(t, err)
+		mirrored, err := ps.CreatePost(context.Background(), "mirrored", "mirror", models.CreatePost{
+			Title: "story", Link: "https://a.example/story", CanonicalURL: "https://a.example/story", PubDate: published,
+		})
+		require.NoError(t, err, "the same link in another feed is kept")
+		require.Equal(t, "original", mirrored.ClusterID)
+
+		hash := uint64(0xF0F0F0F0F0F0F0F0)
+		near := hash ^ 0b111
+		first, err := ps.CreatePost(context.Background(), "hashed", rss_id, models.CreatePost{
+			Title: "hashed", Link: "https://c.example/1", SimHash: &hash, PubDate: published,
+		})
+		require.NoError(t, err)
+		similar, err := ps.CreatePost(context.Background(), "similar", "aggregator", models.CreatePost{
+			Title: "similar", Link: "https://d.example/1", SimHash: &near, PubDate: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, first.ClusterID, similar.ClusterID)
+
+		other, err := ps.CreatePost(context.Background(), "rewritten", "aggregator", models.CreatePost{
+			Title: "rewritten", Link: "https://e.example/1", PubDate: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "rewritten", other.ClusterID)
+		err = ps.SetCanonical(context.Background(), "rewritten", "https://a.example/story")
+		require.NoError(t, err)
+		post, err := ps.GetByID(context.Background(), "rewritten")
+		require.NoError(t, err)
+		require.Equal(t, "original", post.ClusterID)
+		require.Equal(t, "Aggregator", post.FeedTitle)
+
+		series, err := ps.CreatePost(context.Background(), "series", rss_id, models.CreatePost{
+			Title: "series", Link: "https://c.example/2", SimHash: &hash, PubDate: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "series", series.ClusterID)
+
+		for _, id := range []string{"original", "copy", "mirrored", "hashed", "similar", "rewritten", "series"} {
+			_, err = ps.DeletePost(context.Background(), id)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("prune posts past retention", func(t *testing.T) {
+		var meta models.RSSMeta
+		meta.Channel.Title = "Archive"
+		_, err := rs.Create(context.Background(), "archive", "archive-link", meta)
+		require.NoError(t, err)
+		one := 1
+		_, err = rs.SetRetention(context.Background(), "archive", models.UpdateRetentionBody{MaxItems: &one})
+		require.NoError(t, err)
+
+		now := time.Now()
+		for id, age := range map[string]time.Duration{"old": 100 * 24 * time.Hour, "older": time.Hour, "newest": 0} {
+			_, err = ps.CreatePost(context.Background(), id, "archive", models.CreatePost{
+				Title: id, Link: "https://archive.example/" + id, PubDate: now.Add(-age).Format(time.RFC3339),
+			})
+			require.NoError(t, err)
+		}
+		yes := true
+		_, err = ps.SetState(context.Background(), "reader", "older", nil, &yes)
+		require.NoError(t, err)
+
+		// long limits must not overflow when converted to seconds
+		forever := 36500
+		_, err = rs.SetRetention(context.Background(), rss_id, models.UpdateRetentionBody{MaxAgeDays: &forever})
+		require.NoError(t, err)
+
+		policy := models.RetentionPolicy{MaxAge: 30 * 24 * time.Hour}
+		want := []models.PruneReport{{RssID: "archive", Title: "Archive", Posts: 1}}
+		reports, err := ps.Prune(context.Background(), policy, now, true)
+		require.NoError(t, err)
+		require.Equal(t, want, reports)
+		_, err = ps.GetByID(context.Background(), "old")
+		require.NoError(t, err)
+
+		reports, err = ps.Prune(context.Background(), policy, now, false)
+		require.NoError(t, err)
+		require.Equal(t, want, reports)
+		_, err = ps.GetByID(context.Background(), "old")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = ps.GetByID(context.Background(), id)
+		require.NoError(t, err)
+
+	})
+
+	t.Run("orphaned feeds keep starred posts", func(t *testing.T) {
+		now := time.Now()
+		_, _, err := rs.MarkOrphans(context.Background(), now)
+		require.NoError(t, err)
+
+		deleted, kept, err := rs.DeleteOrphans(context.Background(), now)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, deleted)
+		require.EqualValues(t, 2, kept)
+
+		for _, id := range []string{"aggregator", "mirror"} {
+			_, err = rs.FindByID(context.Background(), id)
+			require.ErrorIs(t, err, sql.ErrNoRows)
+		}
+		_, err = ps.GetByID(context.Background(), "newest")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = ps.GetByID(context.Background(), "older")
+		require.NoError(t, err)
+
+		_, err = ps.DeletePost(context.Background(), "older")
 		require.NoError(t, err)
 	})
 
 	t.Run("fetch all posts", func(t *testing.T) {
-		p, err := ps.Fetch(context.Background())
+		p, err := ps.Fetch(context.Background(), models.PostFilter{})
 		require.NoError(t, err)
 
 		if len(p) != 1 {
@@ -5,14 +5,20 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"ogugu/internal/models"
+	"ogugu/internal/urlnorm"
 )
 
 const dbtimeout = time.Second * 3
 
+// gctimeout bounds DeleteOrphans and BackfillFeedKeys, which may touch many
+// rows at once.
+const gctimeout = time.Minute
+
 var tracer = otel.Tracer("rss service")
 
 type Repository struct {
@@ -44,7 +50,7 @@ func (r *Repository) UpdateField(ctx context.Context, id, field, value any) (mod
 	spanctx, span := tracer.Start(ctx, "update rss feed")
 	defer span.End()
 
-	if field != "link" && field != "last_modified" && field != "fetched" {
+	if field != "link" && field != "last_modified" && field != "fetched" && field != "fetch_full_text" {
 		return models.RssFeed{}, errors.New("field update not permitted")
 	}
 
@@ -56,7 +62,7 @@ func (r *Repository) UpdateField(ctx context.Context, id, field, value any) (mod
 		UPDATE rss
 		SET %s = $1, updated_at = $2
 		WHERE id = $3
-		RETURNING id, title, link, description, fetched, last_modified, created_at, updated_at;
+		RETURNING id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, created_at, updated_at;
 	`, field)
 
 	row := r.db.QueryRowContext(dbctx, query, value, time.Now(), id)
@@ -65,7 +71,14 @@ func (r *Repository) UpdateField(ctx context.Context, id, field, value any) (mod
 		&rss.Title,
 		&rss.Link,
 		&rss.Description,
+		&rss.Image,
+		&rss.Podcast,
+		&rss.Language,
+		&rss.Generator,
+		&rss.Copyright,
+		&rss.IconCheckedAt,
 		&rss.Fetched,
+		&rss.FetchFullText,
 		&rss.LastModified,
 		&rss.CreatedAt,
 		&rss.UpdatedAt,
@@ -84,7 +97,7 @@ func (r *Repository) Fetch(ctx context.Context) ([]models.RssFeed, error) {
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `SELECT id, title, link, description, fetched, last_modified, rss_link, created_at, updated_at FROM rss;`
+	query := `SELECT id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, COALESCE(etag, ''), rss_link, last_fetched_at, orphaned_since, archived_at, created_at, updated_at FROM rss;`
 	rows, err := r.db.QueryContext(dbctx, query)
 	if err != nil {
 		return nil, err
@@ -99,9 +112,20 @@ func (r *Repository) Fetch(ctx context.Context) ([]models.RssFeed, error) {
 			&rss.Title,
 			&rss.Link,
 			&rss.Description,
+			&rss.Image,
+			&rss.Podcast,
+			&rss.Language,
+			&rss.Generator,
+			&rss.Copyright,
+			&rss.IconCheckedAt,
 			&rss.Fetched,
+			&rss.FetchFullText,
 			&rss.LastModified,
+			&rss.ETag,
 			&rss.RSSLink,
+			&rss.LastFetchedAt,
+			&rss.OrphanedSince,
+			&rss.ArchivedAt,
 			&rss.CreatedAt,
 			&rss.UpdatedAt,
 		)
@@ -113,6 +137,96 @@ func (r *Repository) Fetch(ctx context.Context) ([]models.RssFeed, error) {
 	return allrss, nil
 }
 
+// directoryOrder maps the sort options of a FeedQuery to ORDER BY clauses.
+var directoryOrder = map[string]string{
+	"":            "subscribers DESC, last_post_at DESC NULLS LAST, rss.title",
+	"subscribers": "subscribers DESC, last_post_at DESC NULLS LAST, rss.title",
+	"recent":      "last_post_at DESC NULLS LAST, rss.title",
+	"title":       "lower(rss.title), rss.id",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Directory returns the feeds matching q with their subscriber counts and
+// posting frequency.
+func (r *Repository) Directory(ctx context.Context, q models.FeedQuery) ([]models.DirectoryFeed, error) {
+	spanctx, span := tracer.Start(ctx, "search the feed directory")
+	defer span.End()
+
+	order, ok := directoryOrder[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		SELECT rss.id, rss.title, rss.link, rss.description, rss.image, rss.podcast, rss.language, rss.generator,
+		rss.copyright, rss.icon_checked_at, rss.fetched, rss.fetch_full_text, rss.last_modified, rss.rss_link,
+		rss.created_at, rss.updated_at,
+		COALESCE(s.subscribers, 0) AS subscribers,
+		COALESCE(p.recent, 0) * 7 / 30.0,
+		p.last_post_at
+		FROM rss
+		LEFT JOIN (
+			SELECT rss_id, count(*) AS subscribers FROM subscriptions GROUP BY rss_id
+		) s ON s.rss_id = rss.id
+		LEFT JOIN (
+			SELECT rss_id, count(*) FILTER (WHERE pubdate > now() - interval '30 days') AS recent,
+			max(pubdate) AS last_post_at
+			FROM posts GROUP BY rss_id
+		) p ON p.rss_id = rss.id
+		WHERE ($1 = '' OR rss.title ILIKE '%' || $1 || '%' OR rss.description ILIKE '%' || $1 || '%'
+			OR rss.link ILIKE '%' || $1 || '%' OR rss.rss_link ILIKE '%' || $1 || '%')
+		AND ($2 = '' OR replace(lower(rss.language), '_', '-') = $2 OR replace(lower(rss.language), '_', '-') LIKE $2 || '-%')
+		AND ($3 = '' OR EXISTS (
+			SELECT 1 FROM posts INNER JOIN post_categories pc ON pc.post_id = posts.id
+			WHERE posts.rss_id = rss.id AND pc.category_slug = $3
+		) OR lower(regexp_replace(trim(rss.podcast->>'category'), '\s+', '-', 'g')) = $3)
+		ORDER BY ` + order + `;
+	`
+	search := likeEscaper.Replace(strings.TrimSpace(q.Search))
+	language := likeEscaper.Replace(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(q.Language)), "_", "-"))
+	category := strings.ToLower(strings.Join(strings.Fields(q.Category), "-"))
+	rows, err := r.db.QueryContext(dbctx, query, search, language, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []models.DirectoryFeed{}
+	for rows.Next() {
+		var feed models.DirectoryFeed
+		err := rows.Scan(
+			&feed.ID,
+			&feed.Title,
+			&feed.Link,
+			&feed.Description,
+			&feed.Image,
+			&feed.Podcast,
+			&feed.Language,
+			&feed.Generator,
+			&feed.Copyright,
+			&feed.IconCheckedAt,
+			&feed.Fetched,
+			&feed.FetchFullText,
+			&feed.LastModified,
+			&feed.RSSLink,
+			&feed.CreatedAt,
+			&feed.UpdatedAt,
+			&feed.Subscribers,
+			&feed.PostsPerWeek,
+			&feed.LastPostAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, rows.Err()
+}
+
 func (r *Repository) FindByID(ctx context.Context, id string) (models.RssFeed, error) {
 	spanctx, span := tracer.Start(ctx, "fetch rss feed by id")
 	defer span.End()
@@ -121,7 +235,7 @@ func (r *Repository) FindByID(ctx context.Context, id string) (models.RssFeed, e
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `SELECT id, title, link, description, fetched, last_modified, rss_link, created_at, updated_at FROM rss WHERE id = $1;`
+	query := `SELECT id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, rss_link, created_at, updated_at FROM rss WHERE id = $1;`
 
 	row := r.db.QueryRowContext(dbctx, query, id)
 	err := row.Scan(
@@ -129,7 +243,14 @@ func (r *Repository) FindByID(ctx context.Context, id string) (models.RssFeed, e
 		&rss.Title,
 		&rss.Link,
 		&rss.Description,
+		&rss.Image,
+		&rss.Podcast,
+		&rss.Language,
+		&rss.Generator,
+		&rss.Copyright,
+		&rss.IconCheckedAt,
 		&rss.Fetched,
+		&rss.FetchFullText,
 		&rss.LastModified,
 		&rss.RSSLink,
 		&rss.CreatedAt,
@@ -150,7 +271,7 @@ func (r *Repository) FindByLink(ctx context.Context, link string) (models.RssFee
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `SELECT id, title, link, description, fetched, last_modified, rss_link, created_at, updated_at FROM rss WHERE link = $1;`
+	query := `SELECT id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, rss_link, created_at, updated_at FROM rss WHERE link = $1;`
 
 	row := r.db.QueryRowContext(dbctx, query, link)
 	err := row.Scan(
@@ -158,7 +279,55 @@ func (r *Repository) FindByLink(ctx context.Context, link string) (models.RssFee
 		&rss.Title,
 		&rss.Link,
 		&rss.Description,
+		&rss.Image,
+		&rss.Podcast,
+		&rss.Language,
+		&rss.Generator,
+		&rss.Copyright,
+		&rss.IconCheckedAt,
+		&rss.Fetched,
+		&rss.FetchFullText,
+		&rss.LastModified,
+		&rss.RSSLink,
+		&rss.CreatedAt,
+		&rss.UpdatedAt,
+	)
+	if err != nil {
+		return models.RssFeed{}, err
+	}
+
+	return rss, nil
+}
+
+// FindByFeedURL returns the feed whose feed url is rss_link or a variant
+// of it with the same urlnorm.FeedKey. Feeds whose key was not backfilled
+// yet are only found by their exact feed url.
+func (r *Repository) FindByFeedURL(ctx context.Context, rss_link string) (models.RssFeed, error) {
+	spanctx, span := tracer.Start(ctx, "fetch rss feed by feed url")
+	defer span.End()
+
+	var rss models.RssFeed
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, rss_link, created_at, updated_at FROM rss
+		WHERE feed_key = $1 OR (feed_key IS NULL AND rss_link = $2)
+		ORDER BY feed_key NULLS LAST LIMIT 1;`
+
+	row := r.db.QueryRowContext(dbctx, query, urlnorm.FeedKey(rss_link), rss_link)
+	err := row.Scan(
+		&rss.ID,
+		&rss.Title,
+		&rss.Link,
+		&rss.Description,
+		&rss.Image,
+		&rss.Podcast,
+		&rss.Language,
+		&rss.Generator,
+		&rss.Copyright,
+		&rss.IconCheckedAt,
 		&rss.Fetched,
+		&rss.FetchFullText,
 		&rss.LastModified,
 		&rss.RSSLink,
 		&rss.CreatedAt,
@@ -171,6 +340,8 @@ func (r *Repository) FindByLink(ctx context.Context, link string) (models.RssFee
 	return rss, nil
 }
 
+// Create inserts a feed. It returns sql.ErrNoRows when a feed with the same
+// urlnorm.FeedKey or feed url exists.
 func (r *Repository) Create(ctx context.Context, id, rss_link string, body models.RSSMeta) (models.RssFeed, error) {
 	spanctx, span := tracer.Start(ctx, "insert rss feed")
 	defer span.End()
@@ -181,17 +352,32 @@ func (r *Repository) Create(ctx context.Context, id, rss_link string, body model
 	defer cancel()
 
 	query := `
-		INSERT INTO rss (id, title, link, description, last_modified, rss_link, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, title, link, description, fetched, last_modified, rss_link, created_at, updated_at;
+		INSERT INTO rss (
+			id, title, link, description, image, podcast, language, generator, copyright, last_modified, rss_link,
+			feed_key, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT DO NOTHING
+		RETURNING id, title, link, description, image, podcast, language, generator, copyright, icon_checked_at, fetched, fetch_full_text, last_modified, rss_link, created_at, updated_at;
 	`
-	row := r.db.QueryRowContext(dbctx, query, id, body.Channel.Title, body.Channel.Link, body.Channel.Description, body.Channel.LastModified, rss_link, time.Now(), time.Now())
+	row := r.db.QueryRowContext(
+		dbctx, query, id, body.Channel.Title, body.Channel.Link, body.Channel.Description, body.Channel.Image,
+		body.Channel.Podcast, body.Channel.Language, body.Channel.Generator, body.Channel.Copyright,
+		body.Channel.LastModified, rss_link, urlnorm.FeedKey(rss_link), time.Now(), time.Now(),
+	)
 	err := row.Scan(
 		&rss.ID,
 		&rss.Title,
 		&rss.Link,
 		&rss.Description,
+		&rss.Image,
+		&rss.Podcast,
+		&rss.Language,
+		&rss.Generator,
+		&rss.Copyright,
+		&rss.IconCheckedAt,
 		&rss.Fetched,
+		&rss.FetchFullText,
 		&rss.LastModified,
 		&rss.RSSLink,
 		&rss.CreatedAt,
@@ -203,3 +389,307 @@ func (r *Repository) Create(ctx context.Context, id, rss_link string, body model
 
 	return rss, nil
 }
+
+// UpdateValidators records a successful fetch along with the cache
+// validators to send on the next one. A nil last_modified clears it so the
+// next fetch does not send If-Modified-Since.
+func (r *Repository) UpdateValidators(ctx context.Context, id, etag string, last_modified *time.Time) error {
+	spanctx, span := tracer.Start(ctx, "update rss feed validators")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE rss
+		SET fetched = TRUE, etag = NULLIF($1, ''), last_modified = $2, updated_at = $3
+		WHERE id = $4;
+	`
+	_, err := r.db.ExecContext(dbctx, query, etag, last_modified, time.Now(), id)
+	return err
+}
+
+// UpdateMetadata refreshes the channel metadata of a feed after a fetch.
+// An empty title or link keeps the stored one.
+func (r *Repository) UpdateMetadata(ctx context.Context, id string, body models.RSSMeta) error {
+	spanctx, span := tracer.Start(ctx, "update rss feed metadata")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE rss
+		SET title = COALESCE(NULLIF($1, ''), title), link = COALESCE(NULLIF($2, ''), link), description = $3,
+			image = $4, podcast = $5, language = $6, generator = $7, copyright = $8, updated_at = $9
+		WHERE id = $10;
+	`
+	ch := body.Channel
+	_, err := r.db.ExecContext(
+		dbctx, query, ch.Title, ch.Link, ch.Description, ch.Image, ch.Podcast, ch.Language, ch.Generator, ch.Copyright,
+		time.Now(), id,
+	)
+	return err
+}
+
+// SetIcon stores the favicon of a feed's site.
+func (r *Repository) SetIcon(ctx context.Context, id string, icon models.FeedIcon) error {
+	spanctx, span := tracer.Start(ctx, "set rss feed icon")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE rss SET icon = $1, icon_type = $2, icon_url = $3, icon_checked_at = $4, updated_at = $4
+		WHERE id = $5;
+	`
+	_, err := r.db.ExecContext(dbctx, query, icon.Data, icon.ContentType, icon.URL, time.Now(), id)
+	return err
+}
+
+// MarkIconChecked records a favicon lookup that found nothing, keeping any
+// icon stored before.
+func (r *Repository) MarkIconChecked(ctx context.Context, id string) error {
+	spanctx, span := tracer.Start(ctx, "mark rss feed icon checked")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE rss SET icon_checked_at = $1 WHERE id = $2;`
+	_, err := r.db.ExecContext(dbctx, query, time.Now(), id)
+	return err
+}
+
+// GetIcon returns sql.ErrNoRows when the feed does not exist or has no icon.
+func (r *Repository) GetIcon(ctx context.Context, id string) (models.FeedIcon, error) {
+	spanctx, span := tracer.Start(ctx, "get rss feed icon")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	var icon models.FeedIcon
+	query := `SELECT icon, icon_type, icon_url, icon_checked_at FROM rss WHERE id = $1 AND icon IS NOT NULL;`
+	err := r.db.QueryRowContext(dbctx, query, id).Scan(&icon.Data, &icon.ContentType, &icon.URL, &icon.UpdatedAt)
+	if err != nil {
+		return models.FeedIcon{}, err
+	}
+	return icon, nil
+}
+
+func (r *Repository) GetRetention(ctx context.Context, id string) (models.FeedRetention, error) {
+	spanctx, span := tracer.Start(ctx, "get rss retention")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT id, retention_days, retention_max_items FROM rss WHERE id = $1;`
+	var f models.FeedRetention
+	err := r.db.QueryRowContext(dbctx, query, id).Scan(&f.RssID, &f.MaxAgeDays, &f.MaxItems)
+	if err != nil {
+		return models.FeedRetention{}, err
+	}
+	return f, nil
+}
+
+// SetRetention overrides the retention policy of a feed. It returns
+// sql.ErrNoRows when the feed does not exist.
+func (r *Repository) SetRetention(ctx context.Context, id string, body models.UpdateRetentionBody) (models.FeedRetention, error) {
+	spanctx, span := tracer.Start(ctx, "set rss retention")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE rss SET retention_days = $1, retention_max_items = $2, updated_at = $3
+		WHERE id = $4
+		RETURNING id, retention_days, retention_max_items;
+	`
+	var f models.FeedRetention
+	row := r.db.QueryRowContext(dbctx, query, body.MaxAgeDays, body.MaxItems, time.Now(), id)
+	if err := row.Scan(&f.RssID, &f.MaxAgeDays, &f.MaxItems); err != nil {
+		return models.FeedRetention{}, err
+	}
+	return f, nil
+}
+
+// MarkFetched records that the worker fetched a feed at fetched_at.
+func (r *Repository) MarkFetched(ctx context.Context, id string, fetched_at time.Time) error {
+	spanctx, span := tracer.Start(ctx, "mark rss fetched")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE rss SET last_fetched_at = $1 WHERE id = $2;`
+	_, err := r.db.ExecContext(dbctx, query, fetched_at, id)
+	return err
+}
+
+// MarkOrphans marks the feeds that lost their last subscriber as orphaned
+// since now, and restores the orphaned or archived feeds that have been
+// subscribed to again. It returns how many feeds were orphaned and how
+// many restored.
+func (r *Repository) MarkOrphans(ctx context.Context, now time.Time) (int64, int64, error) {
+	spanctx, span := tracer.Start(ctx, "mark orphaned rss feeds")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE rss SET orphaned_since = $1
+		WHERE orphaned_since IS NULL
+		AND NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.rss_id = rss.id);
+	`
+	res, err := r.db.ExecContext(dbctx, query, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	orphaned, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query = `
+		UPDATE rss SET orphaned_since = NULL, archived_at = NULL
+		WHERE orphaned_since IS NOT NULL
+		AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.rss_id = rss.id);
+	`
+	res, err = r.db.ExecContext(dbctx, query)
+	if err != nil {
+		return 0, 0, err
+	}
+	restored, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	return orphaned, restored, nil
+}
+
+// Archive archives, at now, the feeds orphaned since before. Archived
+// feeds are no longer fetched.
+func (r *Repository) Archive(ctx context.Context, now, before time.Time) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "archive orphaned rss feeds")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE rss SET archived_at = $1 WHERE archived_at IS NULL AND orphaned_since <= $2;`
+	res, err := r.db.ExecContext(dbctx, query, now, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteOrphans deletes the feeds orphaned since before with their posts.
+// Feeds with starred posts are kept with only those posts until they are
+// unstarred. It returns how many feeds were deleted and how many kept.
+func (r *Repository) DeleteOrphans(ctx context.Context, before time.Time) (int64, int64, error) {
+	spanctx, span := tracer.Start(ctx, "delete orphaned rss feeds")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, gctimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	orphaned := `
+		rss.orphaned_since <= $1
+		AND NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.rss_id = rss.id)
+	`
+	query := `
+		DELETE FROM posts USING rss
+		WHERE posts.rss_id = rss.id AND ` + orphaned + `
+		AND NOT EXISTS (
+			SELECT 1 FROM post_states ps WHERE ps.post_id = posts.id AND ps.starred_at IS NOT NULL
+		);
+	`
+	if _, err := tx.ExecContext(dbctx, query, before); err != nil {
+		return 0, 0, err
+	}
+
+	query = `
+		DELETE FROM rss
+		WHERE ` + orphaned + `
+		AND NOT EXISTS (SELECT 1 FROM posts WHERE posts.rss_id = rss.id);
+	`
+	res, err := tx.ExecContext(dbctx, query, before)
+	if err != nil {
+		return 0, 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var kept int64
+	query = `SELECT count(*) FROM rss WHERE ` + orphaned + `;`
+	if err := tx.QueryRowContext(dbctx, query, before).Scan(&kept); err != nil {
+		return 0, 0, err
+	}
+	return deleted, kept, tx.Commit()
+}
+
+// BackfillFeedKeys sets the feed key of feeds created before keys existed.
+// When several feeds share a key only the oldest gets it, the rest keep
+// their rows but are no longer matched by FindByFeedURL. It returns how many
+// feeds got a key and how many were duplicates.
+func (r *Repository) BackfillFeedKeys(ctx context.Context) (int64, int64, error) {
+	spanctx, span := tracer.Start(ctx, "backfill rss feed keys")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, gctimeout)
+	defer cancel()
+
+	query := `SELECT id, rss_link FROM rss WHERE feed_key IS NULL AND rss_link IS NOT NULL ORDER BY created_at, id;`
+	rows, err := r.db.QueryContext(dbctx, query)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	type feed struct{ id, link string }
+	var feeds []feed
+	for rows.Next() {
+		var f feed
+		if err := rows.Scan(&f.id, &f.link); err != nil {
+			return 0, 0, err
+		}
+		feeds = append(feeds, f)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	query = `
+		UPDATE rss SET feed_key = $1
+		WHERE id = $2 AND NOT EXISTS (SELECT 1 FROM rss WHERE feed_key = $1);
+	`
+	var updated, duplicates int64
+	for _, f := range feeds {
+		res, err := r.db.ExecContext(dbctx, query, urlnorm.FeedKey(f.link), f.id)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, 0, err
+		}
+		if n == 0 {
+			duplicates++
+		}
+		updated += n
+	}
+	return updated, duplicates, nil
+}
@@ -2,7 +2,9 @@ package rss
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -47,6 +49,39 @@ func TestRssService(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("find rss by feed url", func(t *testing.T) {
+		feed, err := rs.FindByFeedURL(context.Background(), "http://www.rsslink.web/rss/")
+		require.NoError(t, err)
+		require.Equal(t, id, feed.ID)
+
+		_, err = rs.Create(context.Background(), "duplicate", "https://www.rsslink.web/rss", models.RSSMeta{})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		_, err = rs.FindByFeedURL(context.Background(), "https://rsslink.web/comments/rss")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("backfill feed keys", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE rss SET feed_key = NULL WHERE id = $1;`, id)
+		require.NoError(t, err)
+
+		// feeds without a key are still found by their exact feed url
+		feed, err := rs.FindByFeedURL(context.Background(), "https://rsslink.web/rss")
+		require.NoError(t, err)
+		require.Equal(t, id, feed.ID)
+		_, err = rs.Create(context.Background(), "duplicate", "https://rsslink.web/rss", models.RSSMeta{})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		updated, duplicates, err := rs.BackfillFeedKeys(context.Background())
+		require.NoError(t, err)
+		require.EqualValues(t, 1, updated)
+		require.Zero(t, duplicates)
+
+		feed, err = rs.FindByFeedURL(context.Background(), "https://rsslink.web/rss?utm_source=feed")
+		require.NoError(t, err)
+		require.Equal(t, id, feed.ID)
+	})
+
 	t.Run("test fetch all rss", func(t *testing.T) {
 		_, err := rs.Fetch(context.Background())
 		require.NoError(t, err)
@@ -63,6 +98,145 @@ func TestRssService(t *testing.T) {
 		}
 	})
 
+	t.Run("enable full text", func(t *testing.T) {
+		feed, err := rs.UpdateField(context.Background(), id, "fetch_full_text", true)
+		require.NoError(t, err)
+		require.True(t, feed.FetchFullText)
+	})
+
+	t.Run("update metadata", func(t *testing.T) {
+		var meta models.RSSMeta
+		meta.Channel.Description = "A new description"
+		meta.Channel.Image = "https://rsslink.web/cover.jpg"
+		meta.Channel.Language = "en-us"
+		meta.Channel.Generator = "Hugo"
+		meta.Channel.Copyright = "CC BY"
+		meta.Channel.Podcast = &models.Podcast{Author: "Ada", Category: "Technology", Type: "episodic"}
+		err := rs.UpdateMetadata(context.Background(), id, meta)
+		require.NoError(t, err)
+
+		feed, err := rs.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, "Example RSS Feed", feed.Title)
+		require.Equal(t, "A new description", feed.Description)
+		require.Equal(t, "https://rsslink.web/cover.jpg", feed.Image)
+		require.Equal(t, "en-us", feed.Language)
+		require.Equal(t, "Hugo", feed.Generator)
+		require.Equal(t, "CC BY", feed.Copyright)
+		require.Equal(t, meta.Channel.Podcast, feed.Podcast)
+	})
+
+	t.Run("icon", func(t *testing.T) {
+		_, err := rs.GetIcon(context.Background(), id)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+
+		err = rs.MarkIconChecked(context.Background(), id)
+		require.NoError(t, err)
+		feed, err := rs.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, feed.IconCheckedAt)
+
+		err = rs.SetIcon(context.Background(), id, models.FeedIcon{
+			URL: "https://rsslink.web/favicon.ico", ContentType: "image/x-icon", Data: []byte{0, 0, 1, 0},
+		})
+		require.NoError(t, err)
+		icon, err := rs.GetIcon(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, "image/x-icon", icon.ContentType)
+		require.Equal(t, []byte{0, 0, 1, 0}, icon.Data)
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		retention, err := rs.GetRetention(context.Background(), id)
+		require.NoError(t, err)
+		require.Nil(t, retention.MaxAgeDays)
+		require.Nil(t, retention.MaxItems)
+
+		days := 30
+		retention, err = rs.SetRetention(context.Background(), id, models.UpdateRetentionBody{MaxAgeDays: &days})
+		require.NoError(t, err)
+		require.Equal(t, 30, *retention.MaxAgeDays)
+		require.Nil(t, retention.MaxItems)
+
+		_, err = rs.SetRetention(context.Background(), "missing", models.UpdateRetentionBody{})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("update validators", func(t *testing.T) {
+		lm := time.Date(2025, 10, 6, 10, 0, 0, 0, time.UTC)
+		err := rs.UpdateValidators(context.Background(), id, `"v1"`, &lm)
+		require.NoError(t, err)
+
+		feeds, err := rs.Fetch(context.Background())
+		require.NoError(t, err)
+		for _, feed := range feeds {
+			if feed.ID == id {
+				require.True(t, feed.Fetched)
+				require.Equal(t, `"v1"`, feed.ETag)
+				require.NotNil(t, feed.LastModified)
+				require.True(t, lm.Equal(*feed.LastModified))
+			}
+		}
+	})
+
+	t.Run("clear last modified", func(t *testing.T) {
+		err := rs.UpdateValidators(context.Background(), id, `"v2"`, nil)
+		require.NoError(t, err)
+
+		feed, err := rs.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		require.Nil(t, feed.LastModified)
+	})
+
+	t.Run("feed directory", func(t *testing.T) {
+		feeds, err := rs.Directory(context.Background(), models.FeedQuery{Search: "example", Language: "EN"})
+		require.NoError(t, err)
+		require.Len(t, feeds, 1)
+		require.Equal(t, id, feeds[0].ID)
+		require.Zero(t, feeds[0].Subscribers)
+		require.Nil(t, feeds[0].LastPostAt)
+
+		feeds, err = rs.Directory(context.Background(), models.FeedQuery{Search: "100%", Sort: "title"})
+		require.NoError(t, err)
+		require.Empty(t, feeds)
+
+		feeds, err = rs.Directory(context.Background(), models.FeedQuery{Category: "technology"})
+		require.NoError(t, err)
+		require.Len(t, feeds, 1)
+
+		_, err = rs.Directory(context.Background(), models.FeedQuery{Sort: "random"})
+		require.Error(t, err)
+	})
+
+	t.Run("orphaned feeds", func(t *testing.T) {
+		now := time.Now()
+		orphaned, restored, err := rs.MarkOrphans(context.Background(), now)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, orphaned)
+		require.Zero(t, restored)
+
+		err = rs.MarkFetched(context.Background(), id, now)
+		require.NoError(t, err)
+		archived, err := rs.Archive(context.Background(), now, now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, archived)
+		archived, err = rs.Archive(context.Background(), now, now)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, archived)
+
+		feeds, err := rs.Fetch(context.Background())
+		require.NoError(t, err)
+		require.Len(t, feeds, 1)
+		require.NotNil(t, feeds[0].OrphanedSince)
+		require.NotNil(t, feeds[0].ArchivedAt)
+		require.NotNil(t, feeds[0].LastFetchedAt)
+
+		deleted, kept, err := rs.DeleteOrphans(context.Background(), now.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+		require.Zero(t, kept)
+	})
+
 	t.Run("delete rss", func(t *testing.T) {
 		n, err := rs.DeleteByID(context.Background(), id)
 		require.NoError(t, err)
@@ -7,6 +7,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"ogugu/internal/models"
+	"ogugu/internal/repository/posts"
 )
 
 const dbtimeout = time.Second * 3
@@ -46,6 +47,12 @@ func (r *Repository) CreateSub(ctx context.Context, id, user_id, rss_id string)
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO subscriptions (id, user_id, rss_id, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5)
@@ -53,13 +60,23 @@ func (r *Repository) CreateSub(ctx context.Context, id, user_id, rss_id string)
 	`
 
 	var sub models.Subscription
-	row := r.db.QueryRowContext(dbctx, query, id, user_id, rss_id, time.Now(), time.Now())
-	err := row.Scan(&sub.ID, &sub.UserID, &sub.CreatedAt, &sub.UpdatedAt)
+	row := tx.QueryRowContext(dbctx, query, id, user_id, rss_id, time.Now(), time.Now())
+	err = row.Scan(&sub.ID, &sub.UserID, &sub.CreatedAt, &sub.UpdatedAt)
 	if err != nil {
 		return models.Subscription{}, err
 	}
 
-	return sub, nil
+	// an orphaned or archived feed is fetched again as soon as someone
+	// subscribes, without waiting for the next gc run
+	query = `
+		UPDATE rss SET orphaned_since = NULL, archived_at = NULL
+		WHERE id = $1 AND (orphaned_since IS NOT NULL OR archived_at IS NOT NULL);
+	`
+	if _, err := tx.ExecContext(dbctx, query, rss_id); err != nil {
+		return models.Subscription{}, err
+	}
+
+	return sub, tx.Commit()
 }
 
 func (r *Repository) GetSubByID(ctx context.Context, id string) (models.Subscription, error) {
@@ -181,42 +198,136 @@ func (r *Repository) GetSubsByUserID(ctx context.Context, user_id string) ([]mod
 	return subs, nil
 }
 
-func (r *Repository) GetPostFromSubScriptions(ctx context.Context, user_id string) ([]models.Post, error) {
+func (r *Repository) GetPostFromSubScriptions(
+	ctx context.Context, user_id string, filter models.PostFilter,
+) ([]models.Post, error) {
 	spanctx, span := tracer.Start(ctx, "get post that user from rss subscriptions")
 	defer span.End()
 
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 	query := `
-		SELECT posts.id, posts.title, posts.description, posts.link, posts.pubdate, posts.created_at, posts.updated_at
+		SELECT ` + posts.Columns + `
 		FROM subscriptions sub
 		INNER JOIN rss ON rss.id = sub.rss_id
 		INNER JOIN posts ON posts.rss_id = sub.rss_id
-		WHERE sub.user_id = $1;
+		WHERE sub.user_id = $1 AND ` + posts.Filter(2) + `
+		ORDER BY posts.pubdate DESC, posts.id;
 	`
-	rows, err := r.db.QueryContext(dbctx, query, user_id)
+	rows, err := r.db.QueryContext(dbctx, query, append([]any{user_id}, posts.FilterArgs(filter)...)...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	var posts []models.Post
+	var timeline []models.Post
 	for rows.Next() {
-		var post models.Post
-		err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Description,
-			&post.Link,
-			&post.PubDate,
-			&post.CreatedAt,
-			&post.UpdatedAt,
+		post, err := posts.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		timeline = append(timeline, post)
+	}
+
+	return timeline, rows.Err()
+}
+
+// GetRankingCandidates returns the most recent posts from a user's
+// subscriptions, up to limit, with the user's history with each post's feed.
+func (r *Repository) GetRankingCandidates(
+	ctx context.Context, user_id string, filter models.PostFilter, limit int,
+) ([]models.RankCandidate, error) {
+	spanctx, span := tracer.Start(ctx, "get ranking candidates from rss subscriptions")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+	query := `
+		WITH feeds AS (
+			SELECT sub.rss_id,
+			count(ps.read_at) AS reads,
+			count(ps.starred_at) AS stars,
+			count(p.id) AS posts,
+			count(p.id) FILTER (WHERE p.pubdate > now() - interval '30 days') * 7 / 30.0 AS posts_per_week
+			FROM subscriptions sub
+			LEFT JOIN posts p ON p.rss_id = sub.rss_id
+			LEFT JOIN post_states ps ON ps.post_id = p.id AND ps.user_id = sub.user_id
+			WHERE sub.user_id = $1
+			GROUP BY sub.rss_id
 		)
+		SELECT ` + posts.Columns + `, feeds.reads, feeds.stars, feeds.posts, feeds.posts_per_week
+		FROM feeds
+		INNER JOIN posts ON posts.rss_id = feeds.rss_id
+		WHERE ` + posts.Filter(2) + `
+		ORDER BY posts.pubdate DESC
+		LIMIT $4;
+	`
+	args := append([]any{user_id}, posts.FilterArgs(filter)...)
+	rows, err := r.db.QueryContext(dbctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.RankCandidate
+	for rows.Next() {
+		var c models.RankCandidate
+		var feed models.FeedSignals
+		c.Post, err = posts.Scan(scanner{rows, []any{&feed.Reads, &feed.Stars, &feed.Posts, &feed.PostsPerWeek}})
 		if err != nil {
 			return nil, err
 		}
+		c.Feed = feed
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
 
-		posts = append(posts, post)
+// GetUnreadPosts returns the newest posts from a user's subscriptions that
+// arrived after since and that the user has not read, up to limit.
+func (r *Repository) GetUnreadPosts(
+	ctx context.Context, user_id string, since time.Time, limit int,
+) ([]models.Post, error) {
+	spanctx, span := tracer.Start(ctx, "get unread posts from rss subscriptions")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+	query := `
+		SELECT ` + posts.Columns + `
+		FROM subscriptions sub
+		INNER JOIN posts ON posts.rss_id = sub.rss_id
+		LEFT JOIN post_states ps ON ps.post_id = posts.id AND ps.user_id = sub.user_id
+		WHERE sub.user_id = $1 AND ps.read_at IS NULL AND posts.created_at > $2
+		ORDER BY posts.pubdate DESC
+		LIMIT $3;
+	`
+	rows, err := r.db.QueryContext(dbctx, query, user_id, since, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	var unread []models.Post
+	for rows.Next() {
+		post, err := posts.Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		unread = append(unread, post)
+	}
+
+	return unread, rows.Err()
+}
+
+// scanner appends extra destinations to the columns read by posts.Scan.
+type scanner struct {
+	rows  *sql.Rows
+	extra []any
+}
 
-	return posts, nil
+func (s scanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.extra...)...)
 }
@@ -38,8 +38,17 @@ func TestSubscriptionService(t *testing.T) {
 	_, err = us.CreateUser(context.Background(), userid, createUser)
 
 	t.Run("create subscription", func(t *testing.T) {
-		_, err := ss.CreateSub(context.Background(), subid, userid, rssid)
+		_, err := db.Exec(`UPDATE rss SET orphaned_since = now(), archived_at = now() WHERE id = $1;`, rssid)
+		require.NoError(t, err)
+
+		_, err = ss.CreateSub(context.Background(), subid, userid, rssid)
 		require.NoError(t, err)
+
+		var orphaned, archived bool
+		row := db.QueryRow(`SELECT orphaned_since IS NOT NULL, archived_at IS NOT NULL FROM rss WHERE id = $1;`, rssid)
+		require.NoError(t, row.Scan(&orphaned, &archived))
+		require.False(t, orphaned, "subscribing restores an orphaned feed")
+		require.False(t, archived, "subscribing unarchives a feed")
 	})
 
 	t.Run("create subscription", func(t *testing.T) {
@@ -67,6 +76,12 @@ func TestSubscriptionService(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("get ranking candidates", func(t *testing.T) {
+		candidates, err := ss.GetRankingCandidates(context.Background(), userid, models.PostFilter{}, 10)
+		require.NoError(t, err)
+		require.Empty(t, candidates)
+	})
+
 	t.Run("unsubscribe", func(t *testing.T) {
 		n, err := ss.DeleteSub(context.Background(), userid, rssid)
 		require.NoError(t, err)
@@ -77,7 +92,7 @@ func TestSubscriptionService(t *testing.T) {
 	})
 
 	t.Run("get subscriptions from user post", func(t *testing.T) {
-		_, err := ss.GetPostFromSubScriptions(context.Background(), userid)
+		_, err := ss.GetPostFromSubScriptions(context.Background(), userid, models.PostFilter{Category: "golang"})
 		require.NoError(t, err)
 	})
 }
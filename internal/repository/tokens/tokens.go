@@ -0,0 +1,87 @@
+package tokens
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("tokens service")
+
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Create stores the hash of a single-use token issued to a user for purpose.
+func (r *Repository) Create(
+	ctx context.Context, id, user_id, purpose, hash string, expiresAt time.Time,
+) error {
+	spanctx, span := tracer.Start(ctx, "create a user token")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	_, err := r.db.ExecContext(dbctx, query, id, user_id, purpose, hash, expiresAt, time.Now())
+	return err
+}
+
+// Consume marks an unused, unexpired token as used and returns the id of the
+// user it was issued to. sql.ErrNoRows is returned for unknown, expired or
+// already used tokens.
+func (r *Repository) Consume(ctx context.Context, purpose, hash string) (string, error) {
+	spanctx, span := tracer.Start(ctx, "consume a user token")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE user_tokens
+		SET used_at = $1
+		WHERE token_hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING user_id;
+	`
+	var userID string
+	row := r.db.QueryRowContext(dbctx, query, time.Now(), hash, purpose)
+	if err := row.Scan(&userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// DeleteForUser removes every token a user holds for purpose, used or not.
+func (r *Repository) DeleteForUser(ctx context.Context, user_id, purpose string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "delete user tokens")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2;`
+	res, err := r.db.ExecContext(dbctx, query, user_id, purpose)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,69 @@
+package tokens_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/tokens"
+	"ogugu/internal/repository/users"
+)
+
+func TestTokenService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	ts := tokens.New(db)
+	us := users.New(db)
+	userid := "userid"
+
+	_, err := us.CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	t.Run("create token", func(t *testing.T) {
+		err := ts.Create(context.Background(), "id1", userid, tokens.PurposePasswordReset, "hash1", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+	})
+
+	t.Run("create expired token", func(t *testing.T) {
+		err := ts.Create(context.Background(), "id2", userid, tokens.PurposePasswordReset, "hash2", time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+	})
+
+	t.Run("consume token with the wrong purpose", func(t *testing.T) {
+		_, err := ts.Consume(context.Background(), "other", "hash1")
+		require.Error(t, err)
+	})
+
+	t.Run("consume token", func(t *testing.T) {
+		id, err := ts.Consume(context.Background(), tokens.PurposePasswordReset, "hash1")
+		require.NoError(t, err)
+		require.Equal(t, userid, id)
+	})
+
+	t.Run("consume token twice", func(t *testing.T) {
+		_, err := ts.Consume(context.Background(), tokens.PurposePasswordReset, "hash1")
+		require.Error(t, err)
+	})
+
+	t.Run("consume expired token", func(t *testing.T) {
+		_, err := ts.Consume(context.Background(), tokens.PurposePasswordReset, "hash2")
+		require.Error(t, err)
+	})
+
+	t.Run("delete tokens for user", func(t *testing.T) {
+		n, err := ts.DeleteForUser(context.Background(), userid, tokens.PurposePasswordReset)
+		require.NoError(t, err)
+		if n != 2 {
+			t.Error("expected to delete two entries from db")
+		}
+	})
+}
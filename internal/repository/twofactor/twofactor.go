@@ -0,0 +1,205 @@
+package twofactor
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/models"
+)
+
+const dbtimeout = time.Second * 3
+
+var tracer = otel.Tracer("two factor service")
+
+type Repository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Get returns the two-factor settings of a user, enrolled or not.
+func (r *Repository) Get(ctx context.Context, user_id string) (models.TwoFactor, error) {
+	spanctx, span := tracer.Start(ctx, "get two factor")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `SELECT user_id, secret, enabled_at, created_at FROM two_factor WHERE user_id = $1;`
+	var tf models.TwoFactor
+	row := r.db.QueryRowContext(dbctx, query, user_id)
+	if err := row.Scan(&tf.UserID, &tf.Secret, &tf.EnabledAt, &tf.CreatedAt); err != nil {
+		return models.TwoFactor{}, err
+	}
+
+	return tf, nil
+}
+
+// Enroll starts enrollment with a new secret, replacing any enrollment that
+// was never completed. It affects no rows when two-factor is already enabled.
+func (r *Repository) Enroll(ctx context.Context, user_id, secret string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "enroll two factor")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO two_factor (user_id, secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at
+		WHERE two_factor.enabled_at IS NULL;
+	`
+	res, err := r.db.ExecContext(dbctx, query, user_id, secret, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Enable completes enrollment with the code at step and stores the hashes
+// of the user's recovery codes.
+func (r *Repository) Enable(ctx context.Context, user_id string, step int64, hashes []string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "enable two factor")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE two_factor SET enabled_at = $1, last_used_step = $2, updated_at = $1
+		WHERE user_id = $3 AND enabled_at IS NULL;
+	`
+	res, err := tx.ExecContext(dbctx, query, time.Now(), step, user_id)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return n, err
+	}
+
+	if err := replaceRecoveryCodes(dbctx, tx, user_id, hashes); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// UseStep records that the code at step was used. It affects no rows when
+// a code at or after step was already used, so every code works only once.
+func (r *Repository) UseStep(ctx context.Context, user_id string, step int64) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "use two factor step")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE two_factor SET last_used_step = $1
+		WHERE user_id = $2 AND enabled_at IS NOT NULL AND (last_used_step IS NULL OR last_used_step < $1);
+	`
+	res, err := r.db.ExecContext(dbctx, query, step, user_id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UseRecoveryCode spends an unused recovery code.
+func (r *Repository) UseRecoveryCode(ctx context.Context, user_id, hash string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "use recovery code")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE recovery_codes SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL;
+	`
+	res, err := r.db.ExecContext(dbctx, query, time.Now(), user_id, hash)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReplaceRecoveryCodes invalidates every recovery code of a user and stores
+// the given hashes instead.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, user_id string, hashes []string) error {
+	spanctx, span := tracer.Start(ctx, "replace recovery codes")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(dbctx, tx, user_id, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, user_id string, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1;`, user_id); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4);`
+	now := time.Now()
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, query, ulid.Make().String(), user_id, hash, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete turns two-factor off for a user and removes their recovery codes.
+func (r *Repository) Delete(ctx context.Context, user_id string) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "delete two factor")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(dbctx, `DELETE FROM two_factor WHERE user_id = $1;`, user_id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(dbctx, `DELETE FROM recovery_codes WHERE user_id = $1;`, user_id); err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
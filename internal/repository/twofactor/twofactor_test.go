@@ -0,0 +1,107 @@
+package twofactor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/models"
+	"ogugu/internal/repository"
+	"ogugu/internal/repository/users"
+)
+
+func TestTwoFactorService(t *testing.T) {
+	db, teardown := repository.SetupTestDB(t)
+	t.Cleanup(teardown)
+
+	ts := New(db)
+	us := users.New(db)
+	userid := "userid"
+
+	_, err := us.CreateUser(context.Background(), userid, models.CreateUserBody{
+		Username: "username",
+		Email:    "user@ogugu.test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	t.Run("enroll", func(t *testing.T) {
+		n, err := ts.Enroll(context.Background(), userid, "secret1")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		n, err = ts.Enroll(context.Background(), userid, "secret2")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		tf, err := ts.Get(context.Background(), userid)
+		require.NoError(t, err)
+		require.Equal(t, "secret2", tf.Secret)
+		require.Nil(t, tf.EnabledAt)
+	})
+
+	t.Run("use step before enabling", func(t *testing.T) {
+		n, err := ts.UseStep(context.Background(), userid, 10)
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		n, err := ts.Enable(context.Background(), userid, 10, []string{"hash1", "hash2"})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		n, err = ts.Enable(context.Background(), userid, 11, []string{"hash3"})
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("enroll while enabled", func(t *testing.T) {
+		n, err := ts.Enroll(context.Background(), userid, "secret3")
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("use step", func(t *testing.T) {
+		n, err := ts.UseStep(context.Background(), userid, 10)
+		require.NoError(t, err)
+		require.Zero(t, n, "the step used to enable cannot be reused")
+
+		n, err = ts.UseStep(context.Background(), userid, 11)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	})
+
+	t.Run("use recovery code", func(t *testing.T) {
+		n, err := ts.UseRecoveryCode(context.Background(), userid, "hash1")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		n, err = ts.UseRecoveryCode(context.Background(), userid, "hash1")
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("replace recovery codes", func(t *testing.T) {
+		err := ts.ReplaceRecoveryCodes(context.Background(), userid, []string{"hash4"})
+		require.NoError(t, err)
+
+		n, err := ts.UseRecoveryCode(context.Background(), userid, "hash2")
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		n, err = ts.UseRecoveryCode(context.Background(), userid, "hash4")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		n, err := ts.Delete(context.Background(), userid)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		_, err = ts.Get(context.Background(), userid)
+		require.Error(t, err)
+	})
+}
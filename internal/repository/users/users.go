@@ -9,6 +9,7 @@ import (
 	"go.opentelemetry.io/otel"
 
 	"ogugu/internal/models"
+	"ogugu/internal/repository/tokens"
 )
 
 const dbtimeout = time.Second * 3
@@ -34,7 +35,7 @@ func (r *Repository) GetUserByID(ctx context.Context, id string) (models.User, e
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `SELECT id, username, email, avatar, created_at, updated_at FROM users WHERE id = $1;`
+	query := `SELECT id, username, email, avatar, email_verified_at, created_at, updated_at FROM users WHERE id = $1;`
 	row := r.db.QueryRowContext(dbctx, query, id)
 
 	err := row.Scan(
@@ -42,6 +43,7 @@ func (r *Repository) GetUserByID(ctx context.Context, id string) (models.User, e
 		&user.Username,
 		&user.Email,
 		&user.Avatar,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -76,7 +78,7 @@ func (r *Repository) CreateUser(ctx context.Context, id string, body models.Crea
 	defer cancel()
 
 	query := `INSERT into users (id, username, email, avatar, created_at, updated_at)
-						VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, username, email, avatar, created_at, updated_at;
+						VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, username, email, avatar, email_verified_at, created_at, updated_at;
 	`
 
 	row := r.db.QueryRowContext(dbctx, query, id, body.Username, body.Email, body.Avatar, time.Now(), time.Now())
@@ -85,6 +87,7 @@ func (r *Repository) CreateUser(ctx context.Context, id string, body models.Crea
 		&user.Username,
 		&user.Email,
 		&user.Avatar,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -108,19 +111,43 @@ func (r *Repository) UpdateUser(ctx context.Context, id string, field, value str
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
+	// a changed email address has to be verified again
+	set := fmt.Sprintf("%s = $1", field)
+	if field == "email" {
+		set += ", email_verified_at = CASE WHEN email = $1 THEN email_verified_at END"
+	}
+
+	tx, err := r.db.BeginTx(dbctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	// links sent to the old address must not verify the new one
+	if field == "email" {
+		query := `
+			DELETE FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2
+				AND EXISTS (SELECT 1 FROM users WHERE id = $1 AND email <> $3);`
+		if _, err := tx.ExecContext(dbctx, query, id, tokens.PurposeEmailVerification, value); err != nil {
+			return models.User{}, err
+		}
+	}
+
 	query := fmt.Sprintf(`
 		UPDATE users
-		SET %s = $1, updated_at = $2
+		SET %s, updated_at = $2
 		WHERE id = $3
-		RETURNING id, username, email, avatar, created_at, updated_at;`, field)
+		RETURNING id, username, email, avatar, email_verified_at, created_at, updated_at;`, set)
 
-	row := r.db.QueryRowContext(dbctx, query, value, time.Now(), id)
+	row := tx.QueryRowContext(dbctx, query, value, time.Now(), id)
 
-	err := row.Scan(
+	err = row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Avatar,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -128,7 +155,7 @@ func (r *Repository) UpdateUser(ctx context.Context, id string, field, value str
 		return models.User{}, err
 	}
 
-	return user, nil
+	return user, tx.Commit()
 }
 
 func (r *Repository) GetUser(ctx context.Context, field, value string) (models.User, error) {
@@ -144,7 +171,7 @@ func (r *Repository) GetUser(ctx context.Context, field, value string) (models.U
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := fmt.Sprintf(`SELECT id, username, email, avatar, created_at, updated_at FROM users WHERE %s = $1;`, field)
+	query := fmt.Sprintf(`SELECT id, username, email, avatar, email_verified_at, created_at, updated_at FROM users WHERE %s = $1;`, field)
 	row := r.db.QueryRowContext(dbctx, query, value)
 
 	err := row.Scan(
@@ -152,6 +179,7 @@ func (r *Repository) GetUser(ctx context.Context, field, value string) (models.U
 		&user.Username,
 		&user.Email,
 		&user.Avatar,
+		&user.EmailVerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -190,7 +218,7 @@ func (r *Repository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
 	defer cancel()
 
-	query := `SELECT id, username, email, avatar, created_at, updated_at FROM users;`
+	query := `SELECT id, username, email, avatar, email_verified_at, created_at, updated_at FROM users;`
 	rows, err := r.db.QueryContext(dbctx, query)
 	if err != nil {
 		return nil, err
@@ -205,6 +233,7 @@ func (r *Repository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 			&user.Username,
 			&user.Email,
 			&user.Avatar,
+			&user.EmailVerifiedAt,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -216,3 +245,69 @@ func (r *Repository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 
 	return users, nil
 }
+
+func (r *Repository) MarkEmailVerified(ctx context.Context, id string) (models.User, error) {
+	spanctx, span := tracer.Start(ctx, "mark user email as verified")
+	defer span.End()
+
+	var user models.User
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, $1), updated_at = $1
+		WHERE id = $2
+		RETURNING id, username, email, avatar, email_verified_at, created_at, updated_at;`
+
+	row := r.db.QueryRowContext(dbctx, query, time.Now(), id)
+
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Avatar,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// IsAdmin reports whether the user with id administers the shared feeds.
+func (r *Repository) IsAdmin(ctx context.Context, id string) (bool, error) {
+	spanctx, span := tracer.Start(ctx, "check user is admin")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	var admin bool
+	query := `SELECT is_admin FROM users WHERE id = $1;`
+	if err := r.db.QueryRowContext(dbctx, query, id).Scan(&admin); err != nil {
+		return false, err
+	}
+	return admin, nil
+}
+
+// SetAdmin grants or revokes the admin role of the user with email and
+// returns how many users were updated.
+func (r *Repository) SetAdmin(ctx context.Context, email string, admin bool) (int64, error) {
+	spanctx, span := tracer.Start(ctx, "set user admin")
+	defer span.End()
+
+	dbctx, cancel := context.WithTimeout(spanctx, dbtimeout)
+	defer cancel()
+
+	query := `UPDATE users SET is_admin = $1, updated_at = $2 WHERE email = $3;`
+	res, err := r.db.ExecContext(dbctx, query, admin, time.Now(), email)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
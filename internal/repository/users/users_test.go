@@ -2,12 +2,15 @@ package users
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"ogugu/internal/models"
 	"ogugu/internal/repository"
+	"ogugu/internal/repository/tokens"
 )
 
 func TestUserService(t *testing.T) {
@@ -64,6 +67,38 @@ func TestUserService(t *testing.T) {
 		}
 	})
 
+	t.Run("mark email as verified", func(t *testing.T) {
+		verified, err := us.MarkEmailVerified(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, verified.EmailVerifiedAt)
+	})
+
+	t.Run("changing email clears verification", func(t *testing.T) {
+		tr := tokens.New(db)
+		err := tr.Create(context.Background(), "tokenid", id, tokens.PurposeEmailVerification, "hash", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		updated, err := us.UpdateUser(context.Background(), id, "email", "new@random.username")
+		require.NoError(t, err)
+		require.Nil(t, updated.EmailVerifiedAt)
+
+		_, err = tr.Consume(context.Background(), tokens.PurposeEmailVerification, "hash")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("admin role", func(t *testing.T) {
+		admin, err := us.IsAdmin(context.Background(), id)
+		require.NoError(t, err)
+		require.False(t, admin)
+
+		n, err := us.SetAdmin(context.Background(), "new@random.username", true)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, n)
+		admin, err = us.IsAdmin(context.Background(), id)
+		require.NoError(t, err)
+		require.True(t, admin)
+	})
+
 	t.Run("delete user", func(t *testing.T) {
 		n, err := us.DeleteUserByID(context.Background(), id)
 		require.NoError(t, err)
@@ -2,26 +2,50 @@ package router
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 
+	"ogugu/internal/config"
+	apikeycontroller "ogugu/internal/controllers/apikeys"
+	"ogugu/internal/controllers/common/request"
 	"ogugu/internal/controllers/common/response"
 	"ogugu/internal/models"
+	"ogugu/internal/ratelimit"
+	"ogugu/internal/repository/apikeys"
+	"ogugu/internal/repository/users"
+	"ogugu/internal/secure"
+	"ogugu/internal/session"
 )
 
 var tracer = otel.Tracer("middleware")
 
-func IsAuthenticated(cache *redis.Client, log *zap.Logger, next http.HandlerFunc) http.HandlerFunc {
+// keyTouchInterval limits how often the last used time of an api key is
+// written back to the database.
+const keyTouchInterval = time.Minute
+
+// IsAuthenticated accepts either a session token or an api key. Api keys are
+// sent as "Bearer ogk_..." or in the X-API-Key header.
+func IsAuthenticated(
+	sessions session.Backend, keys *apikeys.Repository, log *zap.Logger, next http.HandlerFunc,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		spanctx, span := tracer.Start(r.Context(), "Is authenticated middleware")
 		defer span.End()
 
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			authenticateAPIKey(w, r.WithContext(spanctx), keys, log, key, next)
+			return
+		}
+
 		auth := r.Header.Get("Authorization")
 		if !strings.HasPrefix(auth, "Bearer ") {
 			log.Error("session id not found in authorization header")
@@ -36,30 +60,166 @@ func IsAuthenticated(cache *redis.Client, log *zap.Logger, next http.HandlerFunc
 			return
 		}
 
-		value, err := cache.Get(context.Background(), token).Result()
-		if err != nil && err != redis.Nil {
-			log.Error("provided session id does not exist in cache", zap.Error(err))
+		if strings.HasPrefix(token, apikeycontroller.Prefix) {
+			authenticateAPIKey(w, r.WithContext(spanctx), keys, log, token, next)
+			return
+		}
+
+		sess, err := sessions.Authenticate(spanctx, token)
+		if err != nil {
+			if errors.Is(err, session.ErrNotFound) {
+				log.Error("provided session token does not exist or has expired")
+			} else {
+				log.Error("session token cannot be validated into a session", zap.Error(err))
+			}
 			response.Error(w, "You are not logged in", http.StatusUnauthorized, log)
 			return
 		}
 
-		var session models.Session
-		err = json.Unmarshal([]byte(value), &session)
+		ctx := context.WithValue(spanctx, models.AuthSessionKey, sess)
+		req := r.WithContext(ctx)
+
+		next(w, req)
+	}
+}
+
+func authenticateAPIKey(
+	w http.ResponseWriter, r *http.Request, keys *apikeys.Repository, log *zap.Logger, secret string, next http.HandlerFunc,
+) {
+	ctx := r.Context()
+	key, err := keys.GetByHash(ctx, secure.Hash(secret))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Error("provided api key does not exist")
+		} else {
+			log.Error("api key cannot be validated", zap.Error(err))
+		}
+		response.Error(w, "You are not logged in", http.StatusUnauthorized, log)
+		return
+	}
+
+	if key.Scope == models.ScopeRead && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		response.Error(w, "This API key is read-only", http.StatusForbidden, log)
+		return
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= keyTouchInterval {
+		if err := keys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			log.Error("could not record api key usage", zap.String("id", key.ID), zap.Error(err))
+		}
+	}
+
+	sess := models.Session{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		CreatedAt:  key.CreatedAt,
+		LastSeenAt: now,
+		APIKeyID:   key.ID,
+		Scope:      key.Scope,
+	}
+	next(w, r.WithContext(context.WithValue(ctx, models.AuthSessionKey, sess)))
+}
+
+// RequireVerifiedEmail rejects users who have not verified their email
+// address. It is a no-op unless REQUIRE_EMAIL_VERIFICATION is enabled and
+// must be wrapped by IsAuthenticated.
+func RequireVerifiedEmail(userRepo *users.Repository, log *zap.Logger, next http.HandlerFunc) http.HandlerFunc {
+	if !config.Bool("REQUIRE_EMAIL_VERIFICATION", false) {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		spanctx, span := tracer.Start(r.Context(), "Require verified email middleware")
+		defer span.End()
+
+		session := r.Context().Value(models.AuthSessionKey).(models.Session)
+		user, err := userRepo.GetUserByID(spanctx, session.UserID)
 		if err != nil {
-			log.Error("session token cannot be validated into a session", zap.Error(err))
-			response.Error(w, "The provided auth token is invalid", http.StatusUnauthorized, log)
+			log.Error("could not get user", zap.String("userid", session.UserID), zap.Error(err))
+			response.Error(w, "internal server error", http.StatusInternalServerError, log)
 			return
 		}
 
-		if session.ExpiryTime.Before(time.Now()) {
-			log.Error("provided session token has expired")
-			response.Error(w, "You are not logged in", http.StatusUnauthorized, log)
+		if user.EmailVerifiedAt == nil {
+			response.Error(w, "Verify your email address to continue", http.StatusForbidden, log)
 			return
 		}
 
-		ctx := context.WithValue(spanctx, models.AuthSessionKey, session)
-		req := r.WithContext(ctx)
+		next(w, r.WithContext(spanctx))
+	}
+}
 
-		next(w, req)
+// Admins reports whether a user administers the shared feeds.
+type Admins interface {
+	IsAdmin(ctx context.Context, user_id string) (bool, error)
+}
+
+// RequireAdmin rejects users who are not admins. Feeds are shared by every
+// subscriber, so settings that change them for everyone are restricted to
+// admins. It must be wrapped by IsAuthenticated.
+func RequireAdmin(admins Admins, log *zap.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spanctx, span := tracer.Start(r.Context(), "Require admin middleware")
+		defer span.End()
+
+		session := r.Context().Value(models.AuthSessionKey).(models.Session)
+		admin, err := admins.IsAdmin(spanctx, session.UserID)
+		if err != nil {
+			log.Error("could not get user role", zap.String("userid", session.UserID), zap.Error(err))
+			response.Error(w, "internal server error", http.StatusInternalServerError, log)
+			return
+		}
+
+		if !admin {
+			response.Error(w, "Only admins can do this", http.StatusForbidden, log)
+			return
+		}
+
+		next(w, r.WithContext(spanctx))
+	}
+}
+
+// RateLimit limits requests under policy per caller: the api key or user
+// when the request is authenticated, otherwise the client's ip address.
+// Requests are let through when the limiter cannot be reached.
+func RateLimit(limiter *ratelimit.Limiter, policy ratelimit.Policy, log *zap.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spanctx, span := tracer.Start(r.Context(), "Rate limit middleware")
+		defer span.End()
+
+		caller := "ip:" + request.ClientIP(r)
+		if sess, ok := r.Context().Value(models.AuthSessionKey).(models.Session); ok {
+			if sess.APIKeyID != "" {
+				caller = "key:" + sess.APIKeyID
+			} else {
+				caller = "user:" + sess.UserID
+			}
+		}
+
+		res, err := limiter.Allow(spanctx, policy, caller)
+		if err != nil {
+			log.Error("could not check rate limit", zap.String("policy", policy.Name), zap.Error(err))
+			next(w, r.WithContext(spanctx))
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+			response.Error(w, "Too many requests, slow down", http.StatusTooManyRequests, log)
+			return
+		}
+
+		next(w, r.WithContext(spanctx))
 	}
 }
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
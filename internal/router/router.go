@@ -3,6 +3,7 @@ package router
 import (
 	"database/sql"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,30 +12,78 @@ import (
 	"github.com/swaggo/http-swagger/v2"
 	"go.uber.org/zap"
 
+	"ogugu/internal/config"
+	apikeycontroller "ogugu/internal/controllers/apikeys"
 	authcontroller "ogugu/internal/controllers/auth"
+	digestcontroller "ogugu/internal/controllers/digests"
+	filtercontroller "ogugu/internal/controllers/filterrules"
+	oidccontroller "ogugu/internal/controllers/oidc"
 	postcontroller "ogugu/internal/controllers/posts"
 	rsscontroller "ogugu/internal/controllers/rss"
 	subcontroller "ogugu/internal/controllers/subscriptions"
+	"ogugu/internal/digest"
+	"ogugu/internal/fetcher"
+	"ogugu/internal/lockout"
+	"ogugu/internal/mailer"
+	"ogugu/internal/oidc"
+	"ogugu/internal/ratelimit"
+	apiKeyRepo "ogugu/internal/repository/apikeys"
 	authRepo "ogugu/internal/repository/auth"
+	digestRepo "ogugu/internal/repository/digests"
+	filterRuleRepo "ogugu/internal/repository/filterrules"
+	identityRepo "ogugu/internal/repository/identities"
 	postRepo "ogugu/internal/repository/posts"
 	rssRepo "ogugu/internal/repository/rss"
 	subRepo "ogugu/internal/repository/subscriptions"
+	tokenRepo "ogugu/internal/repository/tokens"
+	twoFactorRepo "ogugu/internal/repository/twofactor"
 	userRepo "ogugu/internal/repository/users"
+	"ogugu/internal/safehttp"
+	"ogugu/internal/session"
 )
 
-func Routes(db *sql.DB, cache *redis.Client, logger *zap.Logger) http.Handler {
+func Routes(
+	db *sql.DB,
+	cache *redis.Client,
+	sessions session.Backend,
+	mail mailer.Mailer,
+	logger *zap.Logger,
+) http.Handler {
 	r := chi.NewRouter()
+	if config.Bool("TRUST_PROXY", false) {
+		r.Use(middleware.RealIP)
+	}
 	r.Use(middleware.Logger)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins: []string{"https://*", "http://*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
+		ExposedHeaders: []string{
+			"Link", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+		},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 
+	kr := apiKeyRepo.New(db)
+	rl := ratelimit.New(cache)
+	global := ratelimit.PolicyFromEnv("global", "RATE_LIMIT_GLOBAL", ratelimit.Policy{Limit: 600, Period: time.Minute})
+	user := ratelimit.PolicyFromEnv("user", "RATE_LIMIT_USER", ratelimit.Policy{Limit: 300, Period: time.Minute})
+	auth := ratelimit.PolicyFromEnv("auth", "RATE_LIMIT_AUTH", ratelimit.Policy{Limit: 10, Period: time.Minute})
+	feed := ratelimit.PolicyFromEnv("feed", "RATE_LIMIT_FEED", ratelimit.Policy{Limit: 20, Period: time.Hour})
+
+	limit := func(p ratelimit.Policy, next http.HandlerFunc) http.HandlerFunc {
+		return RateLimit(rl, p, logger, next)
+	}
+	// authed limits authenticated routes per user or api key instead of per ip
+	authed := func(next http.HandlerFunc) http.HandlerFunc {
+		return IsAuthenticated(sessions, kr, logger, limit(user, next))
+	}
+
 	v1 := chi.NewRouter()
+	v1.Use(func(next http.Handler) http.Handler {
+		return limit(global, next.ServeHTTP)
+	})
 	v1.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
@@ -43,26 +92,80 @@ func Routes(db *sql.DB, cache *redis.Client, logger *zap.Logger) http.Handler {
 
 	v1.Get("/swagger/*", httpSwagger.Handler())
 
-	rc := rsscontroller.New(logger, rssRepo.New(db))
-	v1.Post("/feed", rc.CreateRss)
+	ur := userRepo.New(db)
+	// admin wraps handlers that change shared feeds for every subscriber
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return authed(RequireAdmin(ur, logger, next))
+	}
+
+	rc := rsscontroller.New(logger, rssRepo.New(db), fetcher.New(safehttp.NewClient(safehttp.ConfigFromEnv())))
+	v1.Post("/feed", limit(feed, rc.CreateRss))
 	v1.Get("/feed/{id}", rc.FindRssByID)
+	v1.Get("/feed/{id}/icon", rc.Icon)
 	v1.Get("/feed", rc.Fetch)
 	v1.Delete("/feed/{id}", rc.DeleteRssByID)
+	v1.Put("/feed/{id}/full-text", admin(rc.SetFullText))
+	v1.Get("/feed/{id}/retention", authed(rc.GetRetention))
+	v1.Put("/feed/{id}/retention", admin(rc.SetRetention))
 
-	ac := authcontroller.New(cache, logger, userRepo.New(db), authRepo.New(db))
-	v1.Post("/signup", ac.Signup)
-	v1.Post("/signin", ac.Signin)
-	v1.Delete("/signout", IsAuthenticated(cache, logger, ac.Signout))
+	ipLimits, accountLimits := lockout.ConfigFromEnv()
+	ac := authcontroller.New(
+		sessions, cache, logger, mail, ur, authRepo.New(db), tokenRepo.New(db), twoFactorRepo.New(db),
+		lockout.New(cache, ipLimits, accountLimits),
+	)
+	v1.Post("/signup", limit(auth, ac.Signup))
+	v1.Post("/signin", limit(auth, ac.Signin))
+	v1.Post("/signin/2fa", limit(auth, ac.SigninTwoFactor))
+	v1.Delete("/signout", authed(ac.Signout))
+	v1.Post("/token/refresh", limit(auth, ac.RefreshToken))
+	v1.Post("/password/forgot", limit(auth, ac.ForgotPassword))
+	v1.Post("/password/reset", limit(auth, ac.ResetPassword))
+	v1.Get("/sessions", authed(ac.ListSessions))
+	v1.Delete("/sessions", authed(ac.RevokeAllSessions))
+	v1.Delete("/sessions/{id}", authed(ac.RevokeSession))
+	v1.Get("/verify-email", limit(auth, ac.VerifyEmail))
+	v1.Post("/verify-email/resend", authed(limit(auth, ac.ResendVerification)))
+	v1.Post("/2fa/enroll", authed(ac.EnrollTwoFactor))
+	v1.Post("/2fa/enable", authed(ac.EnableTwoFactor))
+	v1.Post("/2fa/disable", authed(ac.DisableTwoFactor))
+	v1.Post("/2fa/recovery-codes", authed(ac.RegenerateRecoveryCodes))
+
+	if cfg := oidc.ConfigFromEnv(); cfg.Enabled() {
+		oc := oidccontroller.New(oidc.New(cfg, nil), cfg.Issuer, cache, sessions, logger, ur, identityRepo.New(db), ac)
+		v1.Get("/oidc/login", limit(auth, oc.Login))
+		v1.Get("/oidc/callback", limit(auth, oc.Callback))
+	}
+
+	kc := apikeycontroller.New(logger, kr)
+	v1.Post("/apikeys", authed(kc.Create))
+	v1.Get("/apikeys", authed(kc.List))
+	v1.Delete("/apikeys/{id}", authed(kc.Revoke))
 
 	pc := postcontroller.New(logger, postRepo.New(db))
 	v1.Get("/posts", pc.FetchPosts)
 	v1.Get("/posts/{id}", pc.GetPostByID)
+	v1.Put("/posts/{id}/state", authed(pc.SetState))
+
+	rules := filterRuleRepo.New(db)
+	fc := filtercontroller.New(logger, rules)
+	v1.Post("/filters", authed(fc.Create))
+	v1.Get("/filters", authed(fc.List))
+	v1.Delete("/filters/{id}", authed(fc.Delete))
+
+	subs := subRepo.New(db)
+	sc := subcontroller.New(cache, logger, subs, rules)
+	v1.Post("/subscriptions", authed(RequireVerifiedEmail(ur, logger, sc.Subscribe)))
+	v1.Delete("/subscriptions", authed(sc.Unsubscribe))
+	v1.Get("/subscriptions", authed(sc.GetUserSubs))
+	v1.Get("/subscriptions/posts", authed(sc.GetPostFromSub))
 
-	sc := subcontroller.New(cache, logger, subRepo.New(db))
-	v1.Post("/subscriptions", IsAuthenticated(cache, logger, sc.Subscribe))
-	v1.Delete("/subscriptions", IsAuthenticated(cache, logger, sc.Unsubscribe))
-	v1.Get("/subscriptions", IsAuthenticated(cache, logger, sc.GetUserSubs))
-	v1.Get("/subscriptions/posts", IsAuthenticated(cache, logger, sc.GetPostFromSub))
+	builder := digest.NewBuilder(subs, rules, config.String("API_URL", "http://localhost:8080"))
+	dc := digestcontroller.New(logger, digestRepo.New(db), ur, builder)
+	v1.Get("/me/digest", authed(dc.Get))
+	v1.Put("/me/digest", authed(dc.Update))
+	v1.Get("/me/digest/preview", authed(dc.Preview))
+	v1.Get("/digest/unsubscribe", limit(auth, dc.Unsubscribe))
+	v1.Post("/digest/unsubscribe", limit(auth, dc.Unsubscribe))
 
 	r.Mount("/v1", v1)
 	return r
@@ -1 +1,42 @@
 package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"ogugu/internal/models"
+)
+
+type admins map[string]bool
+
+func (a admins) IsAdmin(ctx context.Context, user_id string) (bool, error) {
+	return a[user_id], nil
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := RequireAdmin(admins{"admin": true}, zap.NewNop(), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		user string
+		want int
+	}{
+		{"member", http.StatusForbidden},
+		{"admin", http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		t.Run(tc.user, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/feed/feed/retention", nil)
+			req = req.WithContext(context.WithValue(req.Context(), models.AuthSessionKey, models.Session{UserID: tc.user}))
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			require.Equal(t, tc.want, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package safehttp builds http clients for fetching user supplied urls. The
+// clients refuse to connect to loopback, private and other internal
+// addresses, checking the address actually dialled so redirects and DNS
+// rebinding cannot be used to reach them.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ogugu/internal/config"
+)
+
+var (
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+	ErrBlockedScheme  = errors.New("url scheme is not allowed")
+	ErrBlockedPort    = errors.New("destination port is not allowed")
+	ErrTooManyHops    = errors.New("too many redirects")
+	ErrTooLarge       = errors.New("response body is too large")
+)
+
+const DefaultUserAgent = "ogugu/1.0 (+https://github.com/tonievictor/ogugu)"
+
+type Config struct {
+	// Timeout bounds a whole request, including reading the body.
+	Timeout time.Duration
+	// MaxBodySize is the most bytes read from a response body.
+	MaxBodySize  int64
+	MaxRedirects int
+	// AllowedPorts lists the ports that may be connected to.
+	AllowedPorts []int
+	// AllowPrivate turns the address checks off. It exists for local
+	// development and tests and must not be set in production.
+	AllowPrivate bool
+	UserAgent    string
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		Timeout:      config.Duration("FETCH_TIMEOUT", time.Second*15),
+		MaxBodySize:  int64(config.Int("FETCH_MAX_BYTES", 10<<20)),
+		MaxRedirects: config.Int("FETCH_MAX_REDIRECTS", 5),
+		AllowedPorts: parsePorts(config.String("FETCH_ALLOWED_PORTS", "80,443")),
+		AllowPrivate: config.Bool("FETCH_ALLOW_PRIVATE", false),
+		UserAgent:    config.String("FETCH_USER_AGENT", DefaultUserAgent),
+	}
+}
+
+func parsePorts(raw string) []int {
+	var ports []int
+	for _, p := range strings.Split(raw, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+			ports = append(ports, n)
+		}
+	}
+	return ports
+}
+
+// NewClient returns a client that enforces cfg on every request and every
+// redirect it follows.
+func NewClient(cfg Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: time.Second * 10,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return cfg.checkAddress(address)
+		},
+	}
+
+	transport := &http.Transport{
+		// a proxy would make the dialled address meaningless
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       time.Second * 90,
+		TLSHandshakeTimeout:   time.Second * 10,
+		ResponseHeaderTimeout: time.Second * 10,
+	}
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &roundTripper{cfg: cfg, next: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return ErrTooManyHops
+			}
+			return nil
+		},
+	}
+}
+
+// roundTripper checks urls before they are dialled and caps response sizes.
+type roundTripper struct {
+	cfg  Config
+	next http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.cfg.checkURL(req); err != nil {
+		return nil, err
+	}
+
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", rt.cfg.UserAgent)
+	}
+
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.ContentLength > rt.cfg.MaxBodySize {
+		res.Body.Close()
+		return nil, ErrTooLarge
+	}
+	res.Body = &limitedBody{rc: res.Body, remaining: rt.cfg.MaxBodySize}
+	return res, nil
+}
+
+func (cfg Config) checkURL(req *http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("%w: %q", ErrBlockedScheme, req.URL.Scheme)
+	}
+
+	port := req.URL.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[req.URL.Scheme]
+	}
+	return cfg.checkPort(port)
+}
+
+func (cfg Config) checkPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrBlockedPort, port)
+	}
+	for _, allowed := range cfg.AllowedPorts {
+		if n == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d", ErrBlockedPort, n)
+}
+
+// checkAddress runs after DNS resolution with the ip:port about to be
+// connected to.
+func (cfg Config) checkAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkPort(port); err != nil {
+		return err
+	}
+
+	if cfg.AllowPrivate {
+		return nil
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	if !IsPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+// reserved lists special purpose ranges not covered by the netip helpers.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// IsPublic reports whether ip is a globally routable unicast address.
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reserved {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+type limitedBody struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// only fail if there is actually more to read
+		var probe [1]byte
+		if n, _ := b.rc.Read(probe[:]); n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.rc.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+// Get fetches url with client, bound to ctx.
+func Get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
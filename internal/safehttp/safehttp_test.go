@@ -0,0 +1,124 @@
+package safehttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "255.255.255.255", "224.0.0.1",
+		"::1", "::", "fe80::1", "fc00::1", "fd00:ec2::254", "::ffff:127.0.0.1",
+		"::ffff:169.254.169.254", "64:ff9b::a9fe:a9fe", "2002:7f00:1::",
+	}
+	for _, addr := range blocked {
+		require.False(t, IsPublic(netip.MustParseAddr(addr)), addr)
+	}
+
+	allowed := []string{"1.1.1.1", "93.184.216.34", "2606:4700:4700::1111"}
+	for _, addr := range allowed {
+		require.True(t, IsPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/agent":
+			io.WriteString(w, r.UserAgent())
+		case "/large":
+			io.WriteString(w, strings.Repeat("a", 2048))
+		case "/redirect/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/redirect/loop":
+			http.Redirect(w, r, "/redirect/loop", http.StatusFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	cfg := Config{
+		Timeout:      time.Second * 5,
+		MaxBodySize:  1024,
+		MaxRedirects: 3,
+		AllowedPorts: []int{80, 443, port},
+		UserAgent:    DefaultUserAgent,
+	}
+	ctx := context.Background()
+
+	t.Run("block loopback", func(t *testing.T) {
+		_, err := Get(ctx, NewClient(cfg), srv.URL+"/agent")
+		require.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("block hostnames resolving to loopback", func(t *testing.T) {
+		_, err := Get(ctx, NewClient(cfg), "http://localhost:"+u.Port()+"/agent")
+		require.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("block schemes", func(t *testing.T) {
+		for _, link := range []string{"file:///etc/passwd", "ftp://example.com/feed", "gopher://example.com"} {
+			_, err := Get(ctx, NewClient(cfg), link)
+			require.Error(t, err, link)
+		}
+	})
+
+	t.Run("block ports", func(t *testing.T) {
+		_, err := Get(ctx, NewClient(cfg), "http://example.com:6379/")
+		require.ErrorIs(t, err, ErrBlockedPort)
+	})
+
+	private := cfg
+	private.AllowPrivate = true
+	client := NewClient(private)
+
+	t.Run("send user agent", func(t *testing.T) {
+		res, err := Get(ctx, client, srv.URL+"/agent")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, DefaultUserAgent, string(body))
+	})
+
+	t.Run("cap body size", func(t *testing.T) {
+		res, err := Get(ctx, client, srv.URL+"/large")
+		if err == nil {
+			defer res.Body.Close()
+			_, err = io.ReadAll(res.Body)
+		}
+		require.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("check redirects", func(t *testing.T) {
+		_, err := Get(ctx, client, srv.URL+"/redirect/file")
+		require.ErrorIs(t, err, ErrBlockedScheme)
+
+		_, err = Get(ctx, client, srv.URL+"/redirect/loop")
+		require.ErrorIs(t, err, ErrTooManyHops)
+	})
+
+	t.Run("check dialled addresses", func(t *testing.T) {
+		// the dialer runs this on every connection, including redirects
+		require.ErrorIs(t, cfg.checkAddress("169.254.169.254:80"), ErrBlockedAddress)
+		require.ErrorIs(t, cfg.checkAddress("[::1]:443"), ErrBlockedAddress)
+		require.ErrorIs(t, cfg.checkAddress("93.184.216.34:22"), ErrBlockedPort)
+		require.NoError(t, cfg.checkAddress("93.184.216.34:443"))
+	})
+}
@@ -0,0 +1,317 @@
+// Package sanitize cleans html from feeds so it is safe to show to users.
+package sanitize
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowed maps the elements that are kept to the attributes they may keep.
+var allowed = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// dropped elements are removed along with everything inside them. Elements
+// that are neither allowed nor dropped are replaced by their children.
+var dropped = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Audio:    true,
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Canvas:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Video:    true,
+}
+
+// trackers are hosts that only serve analytics images.
+var trackers = []string{
+	"feeds.feedburner.com",
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"www.google-analytics.com",
+	"pixel.quantserve.com",
+	"www.facebook.com/tr",
+}
+
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+// HTML returns raw with everything outside the allowlist removed. Relative
+// links are resolved against base and tracking pixels are dropped.
+func HTML(raw, base string) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	nodes, err := parse(raw)
+	if err != nil {
+		return html.EscapeString(raw)
+	}
+	baseURL, _ := url.Parse(base)
+
+	var b strings.Builder
+	for _, n := range nodes {
+		render(&b, n, baseURL)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Text returns the visible text of raw with whitespace collapsed, cut at a
+// word boundary once it is longer than max runes. A max of 0 means no limit.
+func Text(raw string, max int) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	nodes, err := parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		text(&b, n)
+	}
+	out := strings.Join(strings.Fields(b.String()), " ")
+
+	if max <= 0 || utf8.RuneCountInString(out) <= max {
+		return out
+	}
+	cut := string([]rune(out)[:max])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func parse(raw string) ([]*html.Node, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	return html.ParseFragment(strings.NewReader(raw), body)
+}
+
+func render(b *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if dropped[n.DataAtom] || (n.DataAtom == atom.Img && isPixel(n, base)) {
+		return
+	}
+
+	attrs, ok := allowed[n.DataAtom]
+	if !ok {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(b, c, base)
+		}
+		return
+	}
+
+	kept := filterAttrs(n, attrs, base)
+	if n.DataAtom == atom.Img && !hasAttr(kept, "src") {
+		return
+	}
+	if n.DataAtom == atom.A && hasAttr(kept, "href") {
+		kept = append(kept, html.Attribute{Key: "rel", Val: "noopener noreferrer nofollow"})
+	}
+
+	b.WriteByte('<')
+	b.WriteString(n.Data)
+	for _, a := range kept {
+		b.WriteByte(' ')
+		b.WriteString(a.Key)
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(a.Val))
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+
+	switch n.DataAtom {
+	case atom.Br, atom.Hr, atom.Img:
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		render(b, c, base)
+	}
+	b.WriteString("</")
+	b.WriteString(n.Data)
+	b.WriteByte('>')
+}
+
+func filterAttrs(n *html.Node, names []string, base *url.URL) []html.Attribute {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !slices.Contains(names, a.Key) {
+			continue
+		}
+		if urlAttrs[a.Key] {
+			link, ok := safeURL(a.Val, base, a.Key == "href")
+			if !ok {
+				continue
+			}
+			a.Val = link
+		}
+		kept = append(kept, html.Attribute{Key: a.Key, Val: a.Val})
+	}
+	return kept
+}
+
+// safeURL resolves link against base and rejects schemes that could run
+// script. mailto is only allowed in links.
+func safeURL(link string, base *url.URL, mailto bool) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "":
+	case "mailto":
+		if !mailto {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+// isPixel reports whether an image is a tracking pixel: tiny, hidden or
+// served by a known analytics host.
+func isPixel(n *html.Node, base *url.URL) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "width", "height":
+			if size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(a.Val), "px")); err == nil && size <= 1 {
+				return true
+			}
+		case "style":
+			style := strings.ReplaceAll(strings.ToLower(a.Val), " ", "")
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		case "src":
+			link, ok := safeURL(a.Val, base, false)
+			if !ok {
+				continue
+			}
+			u, _ := url.Parse(link)
+			for _, t := range trackers {
+				if strings.HasPrefix(u.Host+u.Path, t) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var blocks = map[atom.Atom]bool{
+	atom.Blockquote: true, atom.Br: true, atom.Dd: true, atom.Div: true, atom.Dt: true,
+	atom.Figcaption: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Hr: true, atom.Li: true, atom.P: true,
+	atom.Pre: true, atom.Td: true, atom.Th: true, atom.Tr: true,
+}
+
+func text(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if dropped[n.DataAtom] {
+			return
+		}
+	default:
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text(b, c)
+	}
+	if blocks[n.DataAtom] {
+		b.WriteByte(' ')
+	}
+}
+
+func hasAttr(attrs []html.Attribute, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML(t *testing.T) {
+	base := "https://example.test/posts/1"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "just text & more", "just text &amp; more"},
+		{"allowed markup", "<p>Hello <strong>world</strong></p>", "<p>Hello <strong>world</strong></p>"},
+		{"script removed with its content", `<p>a</p><script>alert(1)</script>`, "<p>a</p>"},
+		{"event handlers removed", `<p onclick="alert(1)" class="x">a</p>`, "<p>a</p>"},
+		{"unknown elements unwrapped", `<section><article>text</article></section>`, "text"},
+		{"javascript links removed", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{
+			"relative links resolved",
+			`<a href="../about" target="_blank">about</a>`,
+			`<a href="https://example.test/about" rel="noopener noreferrer nofollow">about</a>`,
+		},
+		{"relative images resolved", `<img src="/a.png" alt="a">`, `<img src="https://example.test/a.png" alt="a">`},
+		{"data images removed", `<img src="data:image/png;base64,AAAA">`, ""},
+		{"tiny pixels removed", `<p>a<img src="https://t.test/p.gif" width="1" height="1"></p>`, "<p>a</p>"},
+		{"hidden images removed", `<img src="/p.gif" style="display: none">`, ""},
+		{"tracker hosts removed", `<img src="https://pixel.wp.com/g.gif?x=1">`, ""},
+		{"feedburner pixels removed", `<img src="http://feeds.feedburner.com/~r/blog/~4/abc">`, ""},
+		{"mailto links kept", `<a href="mailto:me@example.test">me</a>`, `<a href="mailto:me@example.test" rel="noopener noreferrer nofollow">me</a>`},
+		{"empty input", "   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, HTML(tt.in, base))
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	t.Run("markup removed", func(t *testing.T) {
+		in := "<p>Hello <b>there</b>,</p><p>second&nbsp;paragraph</p><script>var x</script>"
+		require.Equal(t, "Hello there, second paragraph", Text(in, 0))
+	})
+
+	t.Run("blocks separated", func(t *testing.T) {
+		require.Equal(t, "one two", Text("<li>one</li><li>two</li>", 0))
+	})
+
+	t.Run("cut at a word boundary", func(t *testing.T) {
+		require.Equal(t, "the quick brown…", Text("the quick brown fox jumps", 18))
+	})
+
+	t.Run("short text untouched", func(t *testing.T) {
+		require.Equal(t, "short", Text("short", 18))
+	})
+}
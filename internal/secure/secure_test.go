@@ -0,0 +1,25 @@
+package secure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecure(t *testing.T) {
+	t.Run("tokens are unique", func(t *testing.T) {
+		a, err := Token(32)
+		require.NoError(t, err)
+		b, err := Token(32)
+		require.NoError(t, err)
+
+		require.NotEqual(t, a, b)
+		require.Len(t, a, 43)
+	})
+
+	t.Run("hash is stable", func(t *testing.T) {
+		require.Equal(t, Hash("token"), Hash("token"))
+		require.NotEqual(t, Hash("token"), Hash("other"))
+		require.Len(t, Hash("token"), 64)
+	})
+}
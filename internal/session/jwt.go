@@ -0,0 +1,151 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidToken = errors.New("invalid signed token")
+
+// minKeyLength is the smallest HS256 key accepted, matching the size of
+// the digest.
+const minKeyLength = 32
+
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys reads a comma separated list of "kid:base64key" pairs.
+func ParseKeys(raw string) ([]Key, error) {
+	var keys []Key
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("signing key %q is not in the kid:base64key format", pair)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q is not valid base64: %w", id, err)
+		}
+		if len(secret) < minKeyLength {
+			return nil, fmt.Errorf("signing key %q must be at least %d bytes", id, minKeyLength)
+		}
+
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("at least one session signing key is required")
+	}
+	return keys, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// claims are the registered JWT claims used by the signed backend plus the
+// refresh token family the token belongs to.
+type claims struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	ID       string `json:"jti"`
+	IssuedAt int64  `json:"iat"`
+	Expiry   int64  `json:"exp"`
+	FamilyID string `json:"fam,omitempty"`
+}
+
+const issuer = "ogugu"
+
+// sign encodes c as an HS256 JWT signed with key.
+func sign(key Key, c claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: "HS256", Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	return unsigned + "." + enc.EncodeToString(mac(key.Secret, unsigned)), nil
+}
+
+// verify checks the signature of token against the key named by its kid and
+// returns its claims. Expiry is left to the caller.
+func verify(keys []Key, token string) (claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims{}, errInvalidToken
+	}
+
+	enc := base64.RawURLEncoding
+	rawHeader, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return claims{}, errInvalidToken
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return claims{}, errInvalidToken
+	}
+	// only the algorithm we issue is accepted, never "none"
+	if header.Algorithm != "HS256" {
+		return claims{}, errInvalidToken
+	}
+
+	var key *Key
+	for i := range keys {
+		if keys[i].ID == header.KeyID {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return claims{}, errInvalidToken
+	}
+
+	signature, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return claims{}, errInvalidToken
+	}
+	if !hmac.Equal(signature, mac(key.Secret, parts[0]+"."+parts[1])) {
+		return claims{}, errInvalidToken
+	}
+
+	rawClaims, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return claims{}, errInvalidToken
+	}
+
+	var c claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil {
+		return claims{}, errInvalidToken
+	}
+	if c.Issuer != issuer {
+		return claims{}, errInvalidToken
+	}
+	return c, nil
+}
+
+func mac(secret []byte, data string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
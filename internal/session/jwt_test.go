@@ -0,0 +1,85 @@
+package session
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedTokens(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	other := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+
+	t.Run("parse keys", func(t *testing.T) {
+		keys, err := ParseKeys("current:" + secret + ", old:" + other)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		require.Equal(t, "current", keys[0].ID)
+	})
+
+	t.Run("reject malformed keys", func(t *testing.T) {
+		_, err := ParseKeys("")
+		require.Error(t, err)
+
+		_, err = ParseKeys(secret)
+		require.Error(t, err)
+
+		_, err = ParseKeys("short:" + base64.StdEncoding.EncodeToString([]byte("short")))
+		require.Error(t, err)
+	})
+
+	keys, err := ParseKeys("current:" + secret + ",old:" + other)
+	require.NoError(t, err)
+
+	c := claims{Issuer: issuer, Subject: "userid", ID: "sessionid", IssuedAt: time.Now().Unix(), Expiry: time.Now().Add(time.Hour).Unix()}
+
+	t.Run("sign and verify", func(t *testing.T) {
+		token, err := sign(keys[0], c)
+		require.NoError(t, err)
+
+		got, err := verify(keys, token)
+		require.NoError(t, err)
+		require.Equal(t, c, got)
+	})
+
+	t.Run("verify token signed with a rotated key", func(t *testing.T) {
+		token, err := sign(keys[1], c)
+		require.NoError(t, err)
+
+		_, err = verify(keys, token)
+		require.NoError(t, err)
+
+		_, err = verify(keys[:1], token)
+		require.Error(t, err)
+	})
+
+	t.Run("reject tampered token", func(t *testing.T) {
+		token, err := sign(keys[0], c)
+		require.NoError(t, err)
+
+		forged := c
+		forged.Subject = "someone else"
+		forgedToken, err := sign(Key{ID: "current", Secret: []byte(strings.Repeat("x", 32))}, forged)
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		forgedParts := strings.Split(forgedToken, ".")
+		_, err = verify(keys, parts[0]+"."+forgedParts[1]+"."+parts[2])
+		require.Error(t, err)
+		_, err = verify(keys, forgedToken)
+		require.Error(t, err)
+	})
+
+	t.Run("reject unsigned token", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"current"}`))
+		token, err := sign(keys[0], c)
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		_, err = verify(keys, header+"."+parts[1]+".")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,405 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/redis/go-redis/v9"
+
+	"ogugu/internal/models"
+	"ogugu/internal/secure"
+)
+
+// touchInterval is the smallest extension of a session's expiry worth
+// writing back to redis, so active users don't cause a write per request.
+const touchInterval = time.Minute
+
+// Redis keeps sessions in redis. Each session is stored under the hash of
+// its bearer token, and every user has an index mapping their session ids
+// to those hashes so sessions can be listed and revoked by id without ever
+// exposing another session's token.
+type Redis struct {
+	cache   *redis.Client
+	cfg     Config
+	refresh refreshTokens
+}
+
+func NewRedis(cache *redis.Client, cfg Config) *Redis {
+	return &Redis{cache: cache, cfg: cfg, refresh: refreshTokens{cache: cache}}
+}
+
+// record is what is persisted for a session. The extra fields are needed to
+// slide and revoke the session but are not part of its public shape.
+type record struct {
+	models.Session
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	FamilyID    string        `json:"family_id,omitempty"`
+}
+
+// indexEntry is what a user's index holds for each session. A copy of the
+// session and its refresh token family are kept so a session whose access
+// token expired can still be listed and revoked while it can be refreshed.
+type indexEntry struct {
+	Hash     string         `json:"hash"`
+	FamilyID string         `json:"family_id,omitempty"`
+	Session  models.Session `json:"session"`
+}
+
+// parseEntry reads an index entry. Entries written before families were
+// indexed only hold the hash of the session's token.
+func parseEntry(value string) (indexEntry, error) {
+	if !strings.HasPrefix(value, "{") {
+		return indexEntry{Hash: value}, nil
+	}
+
+	var entry indexEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return indexEntry{}, err
+	}
+	return entry, nil
+}
+
+func sessionKey(hash string) string {
+	return "session:" + hash
+}
+
+func indexKey(userID string) string {
+	return "user:" + userID + ":sessions"
+}
+
+// Create persists a new session for sess.UserID and returns the bearer token
+// that identifies it. The session expires after the configured idle timeout
+// unless it is used, and never outlives the absolute timeout.
+func (s *Redis) Create(ctx context.Context, sess models.Session) (string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "create session")
+	defer span.End()
+
+	return s.create(spanctx, sess, s.cfg.IdleTimeout, s.cfg.AbsoluteTimeout, "")
+}
+
+func (s *Redis) create(
+	ctx context.Context, sess models.Session, idle, absolute time.Duration, familyID string,
+) (string, models.Session, error) {
+	token, err := secure.Token(32)
+	if err != nil {
+		return "", models.Session{}, err
+	}
+
+	if idle > absolute {
+		idle = absolute
+	}
+
+	now := time.Now()
+	sess.ID = ulid.Make().String()
+	sess.CreatedAt = now
+	sess.LastSeenAt = now
+	sess.ExpiryTime = now.Add(idle)
+	sess.AbsoluteExpiryTime = now.Add(absolute)
+
+	value, err := json.Marshal(record{Session: sess, IdleTimeout: idle, FamilyID: familyID})
+	if err != nil {
+		return "", models.Session{}, err
+	}
+
+	hash := secure.Hash(token)
+	entry, err := json.Marshal(indexEntry{Hash: hash, FamilyID: familyID, Session: sess})
+	if err != nil {
+		return "", models.Session{}, err
+	}
+
+	// the index has to outlive the longest session it points to, and
+	// sessions with a refresh token live as long as the token
+	keep := absolute
+	if familyID != "" && s.cfg.RefreshTokenTTL > keep {
+		keep = s.cfg.RefreshTokenTTL
+	}
+
+	pipe := s.cache.TxPipeline()
+	pipe.Set(ctx, sessionKey(hash), value, idle)
+	pipe.HSet(ctx, indexKey(sess.UserID), sess.ID, entry)
+	pipe.ExpireNX(ctx, indexKey(sess.UserID), keep)
+	pipe.ExpireGT(ctx, indexKey(sess.UserID), keep)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", models.Session{}, err
+	}
+
+	return token, sess, nil
+}
+
+func (s *Redis) CreateWithRefresh(ctx context.Context, sess models.Session) (string, string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "create session with refresh token")
+	defer span.End()
+
+	familyID := ulid.Make().String()
+	access, sess, err := s.create(spanctx, sess, s.cfg.AccessTokenTTL, s.cfg.AccessTokenTTL, familyID)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	refresh, err := s.refresh.issue(spanctx, family{
+		ID:         familyID,
+		UserID:     sess.UserID,
+		SessionID:  sess.ID,
+		ExpiryTime: time.Now().Add(s.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	return access, refresh, sess, nil
+}
+
+func (s *Redis) Refresh(ctx context.Context, token string, meta models.Session) (string, string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "refresh session")
+	defer span.End()
+
+	fam, err := s.refresh.use(spanctx, token)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := s.delete(spanctx, fam.UserID, fam.SessionID); err != nil && !errors.Is(err, ErrNotFound) {
+			return "", "", models.Session{}, err
+		}
+		if err := s.refresh.revoke(spanctx, fam.UserID, fam.ID); err != nil {
+			return "", "", models.Session{}, err
+		}
+		return "", "", models.Session{}, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	if err := s.delete(spanctx, fam.UserID, fam.SessionID); err != nil && !errors.Is(err, ErrNotFound) {
+		return "", "", models.Session{}, err
+	}
+
+	meta.UserID = fam.UserID
+	access, sess, err := s.create(spanctx, meta, s.cfg.AccessTokenTTL, s.cfg.AccessTokenTTL, fam.ID)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	fam.SessionID = sess.ID
+	refresh, err := s.refresh.issue(spanctx, fam)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	return access, refresh, sess, nil
+}
+
+// Authenticate returns the session identified by token and extends its idle
+// window, up to the absolute timeout.
+func (s *Redis) Authenticate(ctx context.Context, token string) (models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "authenticate session")
+	defer span.End()
+
+	hash := secure.Hash(token)
+	rec, err := s.load(spanctx, hash)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	now := time.Now()
+	expiry := now.Add(rec.IdleTimeout)
+	if expiry.After(rec.AbsoluteExpiryTime) {
+		expiry = rec.AbsoluteExpiryTime
+	}
+	if expiry.Sub(rec.ExpiryTime) < touchInterval {
+		return rec.Session, nil
+	}
+
+	rec.LastSeenAt = now
+	rec.ExpiryTime = expiry
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	// XX keeps a session revoked concurrently from being written back
+	err = s.cache.SetXX(spanctx, sessionKey(hash), value, expiry.Sub(now)).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return models.Session{}, err
+	}
+
+	return rec.Session, nil
+}
+
+func (s *Redis) load(ctx context.Context, hash string) (record, error) {
+	value, err := s.cache.Get(ctx, sessionKey(hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return record{}, ErrNotFound
+		}
+		return record{}, err
+	}
+
+	var rec record
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
+		return record{}, err
+	}
+
+	if rec.ExpiryTime.Before(time.Now()) {
+		return record{}, ErrNotFound
+	}
+
+	return rec, nil
+}
+
+// List returns every live session belonging to userID. Sessions whose
+// access token expired are listed while they can be refreshed, other
+// index entries whose session has expired are pruned along the way.
+func (s *Redis) List(ctx context.Context, userID string) ([]models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "list sessions")
+	defer span.End()
+
+	index, err := s.cache.HGetAll(spanctx, indexKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(index) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(index))
+	entries := make([]indexEntry, 0, len(index))
+	keys := make([]string, 0, len(index))
+	for id, value := range index {
+		entry, err := parseEntry(value)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		entries = append(entries, entry)
+		keys = append(keys, sessionKey(entry.Hash))
+	}
+
+	values, err := s.cache.MGet(spanctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []models.Session
+	var stale []string
+	for i, value := range values {
+		str, ok := value.(string)
+		if ok {
+			var rec record
+			if err := json.Unmarshal([]byte(str), &rec); err != nil {
+				return nil, err
+			}
+			sessions = append(sessions, rec.Session)
+			continue
+		}
+
+		sess, live, err := refreshable(spanctx, s.refresh, ids[i], entries[i].FamilyID, entries[i].Session)
+		if err != nil {
+			return nil, err
+		}
+		if !live {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+
+	if len(stale) > 0 {
+		if err := s.cache.HDel(spanctx, indexKey(userID), stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+// Delete revokes the session with the given id if it belongs to userID,
+// along with the refresh token family it was issued from.
+func (s *Redis) Delete(ctx context.Context, userID, id string) error {
+	spanctx, span := tracer.Start(ctx, "delete session")
+	defer span.End()
+
+	value, err := s.cache.HGet(spanctx, indexKey(userID), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	entry, err := parseEntry(value)
+	if err != nil {
+		return err
+	}
+	familyID := entry.FamilyID
+	if familyID == "" {
+		rec, err := s.load(spanctx, entry.Hash)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		familyID = rec.FamilyID
+	}
+
+	if err := s.delete(spanctx, userID, id); err != nil {
+		return err
+	}
+
+	// the family is revoked even when the access token already expired
+	if familyID != "" {
+		return s.refresh.revoke(spanctx, userID, familyID)
+	}
+	return nil
+}
+
+// delete removes a single session, leaving any refresh token family intact.
+func (s *Redis) delete(ctx context.Context, userID, id string) error {
+	value, err := s.cache.HGet(ctx, indexKey(userID), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	entry, err := parseEntry(value)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.cache.TxPipeline()
+	pipe.Del(ctx, sessionKey(entry.Hash))
+	pipe.HDel(ctx, indexKey(userID), id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeleteAll revokes every session and refresh token belonging to userID and
+// reports how many sessions were removed.
+func (s *Redis) DeleteAll(ctx context.Context, userID string) (int, error) {
+	spanctx, span := tracer.Start(ctx, "delete all sessions")
+	defer span.End()
+
+	index, err := s.cache.HGetAll(spanctx, indexKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	keys := []string{indexKey(userID)}
+	for _, value := range index {
+		entry, err := parseEntry(value)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, sessionKey(entry.Hash))
+	}
+
+	if err := s.cache.Del(spanctx, keys...).Err(); err != nil {
+		return 0, err
+	}
+
+	if err := s.refresh.revokeAll(spanctx, userID); err != nil {
+		return 0, err
+	}
+	return len(index), nil
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/database/cache/cachetest"
+	"ogugu/internal/models"
+)
+
+func TestRedisBackend(t *testing.T) {
+	rds, teardown := cachetest.SetupTestCache(t)
+	t.Cleanup(teardown)
+
+	ss := NewRedis(rds, Config{
+		IdleTimeout:     time.Hour,
+		AbsoluteTimeout: time.Hour * 24,
+		AccessTokenTTL:  time.Minute * 15,
+		RefreshTokenTTL: time.Hour * 24,
+	})
+	userid := "userid"
+
+	var token string
+	var sess models.Session
+
+	t.Run("create session", func(t *testing.T) {
+		var err error
+		token, sess, err = ss.Create(context.Background(), models.Session{UserID: userid, UserAgent: "test"})
+		require.NoError(t, err)
+		require.NotEmpty(t, sess.ID)
+		require.NotEqual(t, token, sess.ID)
+	})
+
+	t.Run("authenticate session by token", func(t *testing.T) {
+		got, err := ss.Authenticate(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, sess.ID, got.ID)
+		require.Equal(t, "test", got.UserAgent)
+	})
+
+	t.Run("authenticate does not extend a fresh session", func(t *testing.T) {
+		got, err := ss.Authenticate(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, sess.ExpiryTime.Unix(), got.ExpiryTime.Unix())
+		require.Equal(t, sess.CreatedAt.Add(time.Hour*24).Unix(), got.AbsoluteExpiryTime.Unix())
+	})
+
+	t.Run("authenticate unknown token", func(t *testing.T) {
+		_, err := ss.Authenticate(context.Background(), "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list sessions", func(t *testing.T) {
+		_, _, err := ss.Create(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+
+		sessions, err := ss.List(context.Background(), userid)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+	})
+
+	t.Run("delete session of another user", func(t *testing.T) {
+		err := ss.Delete(context.Background(), "someone else", sess.ID)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete session", func(t *testing.T) {
+		err := ss.Delete(context.Background(), userid, sess.ID)
+		require.NoError(t, err)
+
+		_, err = ss.Authenticate(context.Background(), token)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete all sessions", func(t *testing.T) {
+		n, err := ss.DeleteAll(context.Background(), userid)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		sessions, err := ss.List(context.Background(), userid)
+		require.NoError(t, err)
+		require.Empty(t, sessions)
+	})
+
+	t.Run("rotate refresh token", func(t *testing.T) {
+		access, refresh, sess, err := ss.CreateWithRefresh(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(time.Minute*15), sess.ExpiryTime, time.Second*5)
+
+		newAccess, newRefresh, _, err := ss.Refresh(context.Background(), refresh, models.Session{})
+		require.NoError(t, err)
+		require.NotEqual(t, refresh, newRefresh)
+
+		_, err = ss.Authenticate(context.Background(), access)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = ss.Authenticate(context.Background(), newAccess)
+		require.NoError(t, err)
+
+		_, _, _, err = ss.Refresh(context.Background(), refresh, models.Session{})
+		require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		// reuse revokes the whole family
+		_, err = ss.Authenticate(context.Background(), newAccess)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, _, _, err = ss.Refresh(context.Background(), newRefresh, models.Session{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("revoking a session revokes its refresh token", func(t *testing.T) {
+		_, refresh, sess, err := ss.CreateWithRefresh(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+
+		require.NoError(t, ss.Delete(context.Background(), userid, sess.ID))
+
+		_, _, _, err = ss.Refresh(context.Background(), refresh, models.Session{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("revoking a session after its access token expired revokes its refresh token", func(t *testing.T) {
+		short := NewRedis(rds, Config{
+			IdleTimeout:     time.Hour,
+			AbsoluteTimeout: time.Hour * 24,
+			AccessTokenTTL:  time.Second,
+			RefreshTokenTTL: time.Hour * 24,
+		})
+		access, refresh, sess, err := short.CreateWithRefresh(context.Background(), models.Session{UserID: "expiring"})
+		require.NoError(t, err)
+
+		time.Sleep(time.Second * 2)
+		_, err = short.Authenticate(context.Background(), access)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		sessions, err := short.List(context.Background(), "expiring")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, sess.ID, sessions[0].ID)
+
+		require.NoError(t, short.Delete(context.Background(), "expiring", sess.ID))
+		_, _, _, err = short.Refresh(context.Background(), refresh, models.Session{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
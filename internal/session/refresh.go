@@ -0,0 +1,186 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ogugu/internal/models"
+	"ogugu/internal/secure"
+)
+
+// family tracks the chain of refresh tokens issued from a single sign in.
+// Only the most recently issued token of a family is valid.
+type family struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiryTime time.Time `json:"expires_at"`
+}
+
+type refreshRecord struct {
+	FamilyID string `json:"family_id"`
+	UserID   string `json:"user_id"`
+}
+
+func refreshKey(hash string) string {
+	return "refresh:" + hash
+}
+
+func refreshUsedKey(hash string) string {
+	return "refresh:used:" + hash
+}
+
+func familyKey(id string) string {
+	return "refresh:family:" + id
+}
+
+func familyIndexKey(userID string) string {
+	return "user:" + userID + ":refresh"
+}
+
+// refreshTokens stores rotating refresh tokens in redis. It is shared by
+// every backend; only the access tokens they mint differ.
+type refreshTokens struct {
+	cache *redis.Client
+}
+
+// issue stores a new refresh token as the current token of fam.
+func (rt refreshTokens) issue(ctx context.Context, fam family) (string, error) {
+	token, err := secure.Token(32)
+	if err != nil {
+		return "", err
+	}
+
+	hash := secure.Hash(token)
+	fam.TokenHash = hash
+
+	famValue, err := json.Marshal(fam)
+	if err != nil {
+		return "", err
+	}
+	recValue, err := json.Marshal(refreshRecord{FamilyID: fam.ID, UserID: fam.UserID})
+	if err != nil {
+		return "", err
+	}
+
+	ttl := time.Until(fam.ExpiryTime)
+	pipe := rt.cache.TxPipeline()
+	pipe.Set(ctx, refreshKey(hash), recValue, ttl)
+	pipe.Set(ctx, familyKey(fam.ID), famValue, ttl)
+	pipe.SAdd(ctx, familyIndexKey(fam.UserID), fam.ID)
+	pipe.ExpireNX(ctx, familyIndexKey(fam.UserID), ttl)
+	pipe.ExpireGT(ctx, familyIndexKey(fam.UserID), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// use spends a refresh token and returns its family so the caller can swap
+// the family's access token and issue the next refresh token. Presenting a
+// token that was already spent returns ErrRefreshTokenReused together with
+// the family, which the caller must revoke.
+func (rt refreshTokens) use(ctx context.Context, token string) (family, error) {
+	hash := secure.Hash(token)
+	value, err := rt.cache.Get(ctx, refreshKey(hash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return family{}, ErrNotFound
+		}
+		return family{}, err
+	}
+
+	var rec refreshRecord
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
+		return family{}, err
+	}
+
+	fam, err := rt.load(ctx, rec.FamilyID)
+	if err != nil {
+		return family{}, err
+	}
+
+	remaining := time.Until(fam.ExpiryTime)
+	if remaining <= 0 {
+		return family{}, ErrNotFound
+	}
+
+	first, err := rt.cache.SetNX(ctx, refreshUsedKey(hash), 1, remaining).Result()
+	if err != nil {
+		return family{}, err
+	}
+
+	if !first || fam.TokenHash != hash {
+		return fam, ErrRefreshTokenReused
+	}
+	return fam, nil
+}
+
+func (rt refreshTokens) load(ctx context.Context, id string) (family, error) {
+	value, err := rt.cache.Get(ctx, familyKey(id)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return family{}, ErrNotFound
+		}
+		return family{}, err
+	}
+
+	var fam family
+	if err := json.Unmarshal([]byte(value), &fam); err != nil {
+		return family{}, err
+	}
+	return fam, nil
+}
+
+// refreshable returns sess, the session with id whose access token has
+// expired, and whether familyID can still renew it. The session is shown to
+// expire with its family.
+func refreshable(ctx context.Context, rt refreshTokens, id, familyID string, sess models.Session) (models.Session, bool, error) {
+	if familyID == "" {
+		return models.Session{}, false, nil
+	}
+
+	fam, err := rt.load(ctx, familyID)
+	if errors.Is(err, ErrNotFound) {
+		return models.Session{}, false, nil
+	}
+	if err != nil {
+		return models.Session{}, false, err
+	}
+	if fam.SessionID != id || !fam.ExpiryTime.After(time.Now()) {
+		return models.Session{}, false, nil
+	}
+
+	sess.ExpiryTime = fam.ExpiryTime
+	sess.AbsoluteExpiryTime = fam.ExpiryTime
+	return sess, true, nil
+}
+
+// revoke invalidates every token of a family.
+func (rt refreshTokens) revoke(ctx context.Context, userID, id string) error {
+	pipe := rt.cache.TxPipeline()
+	pipe.Del(ctx, familyKey(id))
+	pipe.SRem(ctx, familyIndexKey(userID), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// revokeAll invalidates every refresh token held by userID.
+func (rt refreshTokens) revokeAll(ctx context.Context, userID string) error {
+	families, err := rt.cache.SMembers(ctx, familyIndexKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{familyIndexKey(userID)}
+	for _, id := range families {
+		keys = append(keys, familyKey(id))
+	}
+	return rt.cache.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+
+	"ogugu/internal/config"
+	"ogugu/internal/models"
+)
+
+var tracer = otel.Tracer("session store")
+
+var ErrNotFound = errors.New("session not found")
+
+// ErrRefreshTokenReused is returned when a refresh token that has already
+// been rotated is presented again. The whole token family is revoked when
+// this happens since either the client or an attacker holds a stolen token.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// Backend issues and validates the bearer tokens that identify a session.
+type Backend interface {
+	// Create starts a session for sess.UserID and returns its bearer token.
+	Create(ctx context.Context, sess models.Session) (string, models.Session, error)
+	// CreateWithRefresh starts a session backed by a short-lived access
+	// token and a long-lived rotating refresh token.
+	CreateWithRefresh(ctx context.Context, sess models.Session) (string, string, models.Session, error)
+	// Refresh rotates a refresh token, returning a new access token and
+	// refresh token pair. meta carries the client details of the caller.
+	Refresh(ctx context.Context, token string, meta models.Session) (string, string, models.Session, error)
+	// Authenticate returns the live session identified by token.
+	Authenticate(ctx context.Context, token string) (models.Session, error)
+	// List returns every live session belonging to userID.
+	List(ctx context.Context, userID string) ([]models.Session, error)
+	// Delete revokes a session belonging to userID and its refresh tokens.
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteAll revokes every session belonging to userID.
+	DeleteAll(ctx context.Context, userID string) (int, error)
+}
+
+type Config struct {
+	// Backend selects the session implementation, "redis" or "signed".
+	Backend string
+	// IdleTimeout is how long a session survives without activity.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout caps the lifetime of a session however active it is.
+	AbsoluteTimeout time.Duration
+	// AccessTokenTTL is the fixed lifetime of access tokens issued with a
+	// refresh token.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is the lifetime of a refresh token family. Rotating a
+	// refresh token does not extend it.
+	RefreshTokenTTL time.Duration
+	// SigningKeys lists the keys of the signed backend as "kid:base64key"
+	// pairs separated by commas. The first key signs new tokens, the rest
+	// are only used to verify tokens issued before a rotation.
+	SigningKeys string
+	// RevocationFailOpen lets the signed backend accept tokens when the
+	// revocation list cannot be reached.
+	RevocationFailOpen bool
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		Backend:            config.String("SESSION_BACKEND", "redis"),
+		IdleTimeout:        config.Duration("SESSION_IDLE_TIMEOUT", time.Hour*24*3),
+		AbsoluteTimeout:    config.Duration("SESSION_ABSOLUTE_TIMEOUT", time.Hour*24*30),
+		AccessTokenTTL:     config.Duration("ACCESS_TOKEN_TTL", time.Minute*15),
+		RefreshTokenTTL:    config.Duration("REFRESH_TOKEN_TTL", time.Hour*24*60),
+		SigningKeys:        config.String("SESSION_SIGNING_KEYS", ""),
+		RevocationFailOpen: config.Bool("SESSION_REVOCATION_FAIL_OPEN", true),
+	}
+}
+
+// New builds the backend selected by cfg.Backend.
+func New(cache *redis.Client, cfg Config) (Backend, error) {
+	switch cfg.Backend {
+	case "", "redis":
+		return NewRedis(cache, cfg), nil
+	case "signed":
+		keys, err := ParseKeys(cfg.SigningKeys)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigned(cache, cfg, keys), nil
+	default:
+		return nil, fmt.Errorf("unknown session backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	rds := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	t.Run("redis backend is the default", func(t *testing.T) {
+		b, err := New(rds, Config{})
+		require.NoError(t, err)
+		require.IsType(t, &Redis{}, b)
+	})
+
+	t.Run("signed backend requires keys", func(t *testing.T) {
+		_, err := New(rds, Config{Backend: "signed"})
+		require.Error(t, err)
+
+		key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+		b, err := New(rds, Config{Backend: "signed", SigningKeys: "v1:" + key})
+		require.NoError(t, err)
+		require.IsType(t, &Signed{}, b)
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		_, err := New(rds, Config{Backend: "cookie"})
+		require.Error(t, err)
+	})
+}
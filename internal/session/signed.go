@@ -0,0 +1,346 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/redis/go-redis/v9"
+
+	"ogugu/internal/models"
+)
+
+// Signed issues self-contained HS256 tokens, so authenticating a request
+// only needs a signature check and a lookup in the revocation list. When
+// the revocation list is unreachable tokens are still accepted if
+// RevocationFailOpen is set.
+//
+// Signed tokens cannot slide: a token lives for the idle timeout from the
+// moment it is issued. Clients that need long-lived logins should use
+// refresh tokens.
+type Signed struct {
+	cache   *redis.Client
+	cfg     Config
+	keys    []Key
+	refresh refreshTokens
+}
+
+func NewSigned(cache *redis.Client, cfg Config, keys []Key) *Signed {
+	return &Signed{cache: cache, cfg: cfg, keys: keys, refresh: refreshTokens{cache: cache}}
+}
+
+// signedEntry is kept in a per-user index so signed sessions can still be
+// listed and revoked by id.
+type signedEntry struct {
+	models.Session
+	FamilyID string `json:"family_id,omitempty"`
+}
+
+func signedIndexKey(userID string) string {
+	return "user:" + userID + ":signed"
+}
+
+func revokedKey(id string) string {
+	return "revoked:" + id
+}
+
+// revokedBeforeKey holds the unix time in milliseconds before which every
+// token of userID was revoked.
+func revokedBeforeKey(userID string) string {
+	return "user:" + userID + ":revoked_before"
+}
+
+// issuedAtMilli returns when a token was issued to the millisecond, so
+// tokens issued in the same second as a revocation are not let through. The
+// session id is a ULID made when the token was issued; iat only has second
+// precision and is rounded down.
+func issuedAtMilli(c claims) int64 {
+	if id, err := ulid.Parse(c.ID); err == nil {
+		return int64(id.Time())
+	}
+	return c.IssuedAt * 1000
+}
+
+func (s *Signed) Create(ctx context.Context, sess models.Session) (string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "create signed session")
+	defer span.End()
+
+	ttl := s.cfg.IdleTimeout
+	if ttl > s.cfg.AbsoluteTimeout {
+		ttl = s.cfg.AbsoluteTimeout
+	}
+	return s.issue(spanctx, sess, ttl, "")
+}
+
+func (s *Signed) issue(ctx context.Context, sess models.Session, ttl time.Duration, familyID string) (string, models.Session, error) {
+	now := time.Now()
+	sess.ID = ulid.Make().String()
+	sess.CreatedAt = now
+	sess.LastSeenAt = now
+	sess.ExpiryTime = now.Add(ttl)
+	sess.AbsoluteExpiryTime = sess.ExpiryTime
+
+	token, err := sign(s.keys[0], claims{
+		Issuer:   issuer,
+		Subject:  sess.UserID,
+		ID:       sess.ID,
+		IssuedAt: now.Unix(),
+		Expiry:   sess.ExpiryTime.Unix(),
+		FamilyID: familyID,
+	})
+	if err != nil {
+		return "", models.Session{}, err
+	}
+
+	value, err := json.Marshal(signedEntry{Session: sess, FamilyID: familyID})
+	if err != nil {
+		return "", models.Session{}, err
+	}
+
+	// sessions with a refresh token are indexed as long as the token lives
+	keep := ttl
+	if familyID != "" && s.cfg.RefreshTokenTTL > keep {
+		keep = s.cfg.RefreshTokenTTL
+	}
+
+	pipe := s.cache.TxPipeline()
+	pipe.HSet(ctx, signedIndexKey(sess.UserID), sess.ID, value)
+	pipe.ExpireNX(ctx, signedIndexKey(sess.UserID), keep)
+	pipe.ExpireGT(ctx, signedIndexKey(sess.UserID), keep)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", models.Session{}, err
+	}
+
+	return token, sess, nil
+}
+
+func (s *Signed) CreateWithRefresh(ctx context.Context, sess models.Session) (string, string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "create signed session with refresh token")
+	defer span.End()
+
+	familyID := ulid.Make().String()
+	access, sess, err := s.issue(spanctx, sess, s.cfg.AccessTokenTTL, familyID)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	refresh, err := s.refresh.issue(spanctx, family{
+		ID:         familyID,
+		UserID:     sess.UserID,
+		SessionID:  sess.ID,
+		ExpiryTime: time.Now().Add(s.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	return access, refresh, sess, nil
+}
+
+func (s *Signed) Refresh(ctx context.Context, token string, meta models.Session) (string, string, models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "refresh signed session")
+	defer span.End()
+
+	fam, err := s.refresh.use(spanctx, token)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if _, err := s.revoke(spanctx, fam.UserID, fam.SessionID); err != nil && !errors.Is(err, ErrNotFound) {
+			return "", "", models.Session{}, err
+		}
+		if err := s.refresh.revoke(spanctx, fam.UserID, fam.ID); err != nil {
+			return "", "", models.Session{}, err
+		}
+		return "", "", models.Session{}, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	if _, err := s.revoke(spanctx, fam.UserID, fam.SessionID); err != nil && !errors.Is(err, ErrNotFound) {
+		return "", "", models.Session{}, err
+	}
+
+	meta.UserID = fam.UserID
+	access, sess, err := s.issue(spanctx, meta, s.cfg.AccessTokenTTL, fam.ID)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	fam.SessionID = sess.ID
+	refresh, err := s.refresh.issue(spanctx, fam)
+	if err != nil {
+		return "", "", models.Session{}, err
+	}
+
+	return access, refresh, sess, nil
+}
+
+// Authenticate verifies token and checks that it has not been revoked.
+func (s *Signed) Authenticate(ctx context.Context, token string) (models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "authenticate signed session")
+	defer span.End()
+
+	c, err := verify(s.keys, token)
+	if err != nil {
+		return models.Session{}, ErrNotFound
+	}
+
+	expiry := time.Unix(c.Expiry, 0)
+	if expiry.Before(time.Now()) {
+		return models.Session{}, ErrNotFound
+	}
+
+	values, err := s.cache.MGet(spanctx, revokedKey(c.ID), revokedBeforeKey(c.Subject)).Result()
+	if err != nil {
+		if !s.cfg.RevocationFailOpen {
+			return models.Session{}, err
+		}
+		span.AddEvent("revocation list unavailable, accepting token")
+	} else {
+		if values[0] != nil {
+			return models.Session{}, ErrNotFound
+		}
+		if before, ok := values[1].(string); ok {
+			ts, err := strconv.ParseInt(before, 10, 64)
+			if err == nil && issuedAtMilli(c) <= ts {
+				return models.Session{}, ErrNotFound
+			}
+		}
+	}
+
+	issued := time.Unix(c.IssuedAt, 0)
+	return models.Session{
+		ID:                 c.ID,
+		UserID:             c.Subject,
+		CreatedAt:          issued,
+		LastSeenAt:         issued,
+		ExpiryTime:         expiry,
+		AbsoluteExpiryTime: expiry,
+	}, nil
+}
+
+func (s *Signed) List(ctx context.Context, userID string) ([]models.Session, error) {
+	spanctx, span := tracer.Start(ctx, "list signed sessions")
+	defer span.End()
+
+	index, err := s.cache.HGetAll(spanctx, signedIndexKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var sessions []models.Session
+	var stale []string
+	for id, value := range index {
+		var entry signedEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, err
+		}
+		if !entry.ExpiryTime.Before(now) {
+			sessions = append(sessions, entry.Session)
+			continue
+		}
+
+		// the access token expired but the session lives on while it can
+		// be refreshed
+		sess, live, err := refreshable(spanctx, s.refresh, id, entry.FamilyID, entry.Session)
+		if err != nil {
+			return nil, err
+		}
+		if !live {
+			stale = append(stale, id)
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+
+	if len(stale) > 0 {
+		if err := s.cache.HDel(spanctx, signedIndexKey(userID), stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+func (s *Signed) Delete(ctx context.Context, userID, id string) error {
+	spanctx, span := tracer.Start(ctx, "delete signed session")
+	defer span.End()
+
+	entry, err := s.revoke(spanctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if entry.FamilyID != "" {
+		return s.refresh.revoke(spanctx, userID, entry.FamilyID)
+	}
+	return nil
+}
+
+// revoke adds a session to the revocation list until its token expires.
+func (s *Signed) revoke(ctx context.Context, userID, id string) (signedEntry, error) {
+	value, err := s.cache.HGet(ctx, signedIndexKey(userID), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return signedEntry{}, ErrNotFound
+		}
+		return signedEntry{}, err
+	}
+
+	var entry signedEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return signedEntry{}, err
+	}
+
+	pipe := s.cache.TxPipeline()
+	if ttl := time.Until(entry.ExpiryTime); ttl > 0 {
+		pipe.Set(ctx, revokedKey(id), 1, ttl)
+	}
+	pipe.HDel(ctx, signedIndexKey(userID), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return signedEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// DeleteAll revokes every indexed session of userID and, as a backstop,
+// rejects any token issued to them before now.
+func (s *Signed) DeleteAll(ctx context.Context, userID string) (int, error) {
+	spanctx, span := tracer.Start(ctx, "delete all signed sessions")
+	defer span.End()
+
+	index, err := s.cache.HGetAll(spanctx, signedIndexKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	longest := s.cfg.IdleTimeout
+	if s.cfg.AccessTokenTTL > longest {
+		longest = s.cfg.AccessTokenTTL
+	}
+
+	pipe := s.cache.TxPipeline()
+	for id, value := range index {
+		var entry signedEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return 0, err
+		}
+		if ttl := time.Until(entry.ExpiryTime); ttl > 0 {
+			pipe.Set(spanctx, revokedKey(id), 1, ttl)
+		}
+	}
+	pipe.Set(spanctx, revokedBeforeKey(userID), time.Now().UnixMilli(), longest)
+	pipe.Del(spanctx, signedIndexKey(userID))
+	if _, err := pipe.Exec(spanctx); err != nil {
+		return 0, err
+	}
+
+	if err := s.refresh.revokeAll(spanctx, userID); err != nil {
+		return 0, err
+	}
+	return len(index), nil
+}
@@ -0,0 +1,129 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"ogugu/internal/database/cache/cachetest"
+	"ogugu/internal/models"
+)
+
+func TestSignedBackend(t *testing.T) {
+	rds, teardown := cachetest.SetupTestCache(t)
+	t.Cleanup(teardown)
+
+	keys, err := ParseKeys("current:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+
+	ss := NewSigned(rds, Config{
+		IdleTimeout:     time.Hour,
+		AbsoluteTimeout: time.Hour * 24,
+		AccessTokenTTL:  time.Minute * 15,
+		RefreshTokenTTL: time.Hour * 24,
+	}, keys)
+	userid := "userid"
+
+	var token string
+	var sess models.Session
+
+	t.Run("create session", func(t *testing.T) {
+		token, sess, err = ss.Create(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+	})
+
+	t.Run("authenticate session", func(t *testing.T) {
+		got, err := ss.Authenticate(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, sess.ID, got.ID)
+		require.Equal(t, userid, got.UserID)
+	})
+
+	t.Run("authenticate forged token", func(t *testing.T) {
+		_, err := ss.Authenticate(context.Background(), token+"x")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list sessions", func(t *testing.T) {
+		sessions, err := ss.List(context.Background(), userid)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+	})
+
+	t.Run("delete session", func(t *testing.T) {
+		require.NoError(t, ss.Delete(context.Background(), userid, sess.ID))
+
+		_, err := ss.Authenticate(context.Background(), token)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("rotate refresh token", func(t *testing.T) {
+		access, refresh, _, err := ss.CreateWithRefresh(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+
+		newAccess, _, _, err := ss.Refresh(context.Background(), refresh, models.Session{})
+		require.NoError(t, err)
+
+		_, err = ss.Authenticate(context.Background(), access)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = ss.Authenticate(context.Background(), newAccess)
+		require.NoError(t, err)
+
+		_, _, _, err = ss.Refresh(context.Background(), refresh, models.Session{})
+		require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = ss.Authenticate(context.Background(), newAccess)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete all sessions", func(t *testing.T) {
+		token, _, err := ss.Create(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+
+		_, err = ss.DeleteAll(context.Background(), userid)
+		require.NoError(t, err)
+
+		_, err = ss.Authenticate(context.Background(), token)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete all revokes unindexed tokens issued the same second", func(t *testing.T) {
+		token, sess, err := ss.Create(context.Background(), models.Session{UserID: userid})
+		require.NoError(t, err)
+		require.NoError(t, rds.HDel(context.Background(), signedIndexKey(userid), sess.ID).Err())
+
+		_, err = ss.DeleteAll(context.Background(), userid)
+		require.NoError(t, err)
+
+		_, err = ss.Authenticate(context.Background(), token)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("revoking a session after its access token expired revokes its refresh token", func(t *testing.T) {
+		short := NewSigned(rds, Config{
+			IdleTimeout:     time.Hour,
+			AbsoluteTimeout: time.Hour * 24,
+			AccessTokenTTL:  time.Second,
+			RefreshTokenTTL: time.Hour * 24,
+		}, keys)
+		access, refresh, sess, err := short.CreateWithRefresh(context.Background(), models.Session{UserID: "expiring"})
+		require.NoError(t, err)
+
+		time.Sleep(time.Second * 2)
+		_, err = short.Authenticate(context.Background(), access)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		sessions, err := short.List(context.Background(), "expiring")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, sess.ID, sessions[0].ID)
+
+		require.NoError(t, short.Delete(context.Background(), "expiring", sess.ID))
+		_, _, _, err = short.Refresh(context.Background(), refresh, models.Session{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
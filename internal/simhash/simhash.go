@@ -0,0 +1,60 @@
+// Package simhash fingerprints text so near-identical texts have
+// fingerprints that differ in few bits.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// MinWords is the fewest words a text needs to be fingerprinted. Shorter
+// texts are too alike by chance to compare.
+const MinWords = 8
+
+// Threshold is the largest distance at which two fingerprints are taken
+// to be of the same text. Feed summaries are short, so it is looser than
+// the few bits used for whole documents.
+const Threshold = 10
+
+// shingle is the number of consecutive words hashed together.
+const shingle = 3
+
+// Hash returns the fingerprint of text and whether it has enough words to
+// have one. Case, punctuation and spacing are ignored.
+func Hash(text string) (uint64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < MinWords {
+		return 0, false
+	}
+
+	var weights [64]int
+	for i := 0; i+shingle <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingle], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for b, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << b
+		}
+	}
+	return fingerprint, true
+}
+
+// Distance is the number of bits in which two fingerprints differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
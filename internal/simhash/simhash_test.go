@@ -0,0 +1,40 @@
+package simhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	story := "Go 1.25 is released with a new garbage collector, faster builds and improved tooling for module authors everywhere"
+
+	t.Run("short texts have no fingerprint", func(t *testing.T) {
+		_, ok := Hash("Weekly update")
+		require.False(t, ok)
+	})
+
+	t.Run("case and punctuation are ignored", func(t *testing.T) {
+		a, ok := Hash(story)
+		require.True(t, ok)
+		b, _ := Hash("GO 1.25 IS RELEASED -- with a new garbage collector; faster builds and improved tooling for module authors everywhere!")
+		require.Equal(t, a, b)
+	})
+
+	t.Run("near duplicates are close", func(t *testing.T) {
+		a, _ := Hash(story)
+		b, _ := Hash(story + " via Hacker News")
+		require.LessOrEqual(t, Distance(a, b), Threshold)
+	})
+
+	t.Run("different stories are far apart", func(t *testing.T) {
+		a, _ := Hash(story)
+		b, _ := Hash("Local bakery wins a regional award for its sourdough bread after a decade of early mornings and hard work")
+		require.Greater(t, Distance(a, b), Threshold)
+	})
+}
+
+func TestDistance(t *testing.T) {
+	require.Equal(t, 0, Distance(42, 42))
+	require.Equal(t, 64, Distance(0, ^uint64(0)))
+}
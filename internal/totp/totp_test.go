@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	// SHA-1 test vectors from RFC 6238 appendix B
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		1111111111: "14050471",
+		1234567890: "89005924",
+		2000000000: "69279037",
+	}
+
+	for unix, want := range vectors {
+		require.Equal(t, want, hotp(key, Step(time.Unix(unix, 0)), 8))
+	}
+
+	secret := encoding.EncodeToString(key)
+	got, err := Code(secret, time.Unix(59, 0))
+	require.NoError(t, err)
+	require.Equal(t, "287082", got)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	current, err := Code(secret, now)
+	require.NoError(t, err)
+
+	t.Run("current code", func(t *testing.T) {
+		step, ok := Validate(secret, current, now)
+		require.True(t, ok)
+		require.Equal(t, Step(now), step)
+	})
+
+	t.Run("previous code", func(t *testing.T) {
+		previous, err := Code(secret, now.Add(-time.Second*Period))
+		require.NoError(t, err)
+		step, ok := Validate(secret, previous, now)
+		require.True(t, ok)
+		require.Equal(t, Step(now)-1, step)
+	})
+
+	t.Run("stale code", func(t *testing.T) {
+		stale, err := Code(secret, now.Add(-time.Second*Period*3))
+		require.NoError(t, err)
+		_, ok := Validate(secret, stale, now)
+		require.False(t, ok)
+	})
+
+	t.Run("lowercase secret", func(t *testing.T) {
+		_, ok := Validate(strings.ToLower(secret), current, now)
+		require.True(t, ok)
+	})
+
+	t.Run("malformed code", func(t *testing.T) {
+		_, ok := Validate(secret, "12345", now)
+		require.False(t, ok)
+	})
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("ogugu", "user@ogugu.test", "JBSWY3DPEHPK3PXP")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/ogugu:user@ogugu.test?"))
+	require.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	require.Contains(t, uri, "issuer=ogugu")
+}
@@ -0,0 +1,87 @@
+// Package urlnorm canonicalizes links so the same page shared with
+// different tracking parameters has one URL.
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// tracking are query parameters that identify a campaign or click rather
+// than a page. Parameters starting with utm_ are always removed.
+var tracking = map[string]bool{
+	"fbclid":     true,
+	"gclid":      true,
+	"dclid":      true,
+	"msclkid":    true,
+	"yclid":      true,
+	"mc_cid":     true,
+	"mc_eid":     true,
+	"_hsenc":     true,
+	"_hsmi":      true,
+	"mkt_tok":    true,
+	"igshid":     true,
+	"ref_src":    true,
+	"ncid":       true,
+	"cmpid":      true,
+	"sr_share":   true,
+	"oly_enc_id": true,
+}
+
+// Canonical returns link with a lowercase scheme and host, no default port,
+// fragment or tracking parameters, and its query sorted. Links that are not
+// absolute http(s) URLs are returned unchanged.
+func Canonical(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return link
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if port != "" {
+		u.Host = host + ":" + port
+	}
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || tracking[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// FeedKey returns a key shared by the links that are taken to be the same
+// feed: the Canonical link without its scheme, "www." prefix or trailing
+// slash. Links that are not absolute http(s) URLs are their own key.
+func FeedKey(link string) string {
+	u, err := url.Parse(Canonical(link))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return link
+	}
+
+	key := strings.TrimPrefix(u.Host, "www.") + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
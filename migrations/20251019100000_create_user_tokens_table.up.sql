@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_tokens (
+	id TEXT PRIMARY KEY NOT NULL UNIQUE,
+	user_id TEXT NOT NULL,
+	purpose TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
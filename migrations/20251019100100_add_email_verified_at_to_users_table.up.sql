@@ -0,0 +1,6 @@
+ALTER TABLE IF EXISTS users
+ADD COLUMN email_verified_at TIMESTAMP;
+
+-- accounts created before verification existed keep working unverified
+-- features, so treat them as verified from the day they signed up
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS rss
+ADD COLUMN etag TEXT;
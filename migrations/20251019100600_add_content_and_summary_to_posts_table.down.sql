@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS posts
+DROP COLUMN content,
+DROP COLUMN summary;
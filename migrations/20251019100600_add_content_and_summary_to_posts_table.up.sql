@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS posts
+ADD COLUMN content TEXT NOT NULL DEFAULT '',
+ADD COLUMN summary TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS rss
+DROP COLUMN fetch_full_text;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN full_content;
@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS rss
+ADD COLUMN fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE IF EXISTS posts
+ADD COLUMN full_content TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE IF EXISTS rss
+DROP COLUMN image,
+DROP COLUMN podcast;
+
+ALTER TABLE IF EXISTS posts
+DROP COLUMN thumbnail,
+DROP COLUMN episode;
+
+DROP TABLE IF EXISTS enclosures;
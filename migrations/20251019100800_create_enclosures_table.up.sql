@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS enclosures (
+	post_id TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	url TEXT NOT NULL,
+	mime_type TEXT NOT NULL DEFAULT '',
+	length BIGINT NOT NULL DEFAULT 0,
+	duration INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (post_id, position),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+ALTER TABLE IF EXISTS posts
+ADD COLUMN thumbnail TEXT NOT NULL DEFAULT '',
+ADD COLUMN episode JSONB;
+
+ALTER TABLE IF EXISTS rss
+ADD COLUMN image TEXT NOT NULL DEFAULT '',
+ADD COLUMN podcast JSONB;
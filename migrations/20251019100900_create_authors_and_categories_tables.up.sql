@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS authors (
+	slug TEXT PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS post_authors (
+	post_id TEXT NOT NULL,
+	author_slug TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+
+	PRIMARY KEY (post_id, author_slug),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (author_slug) REFERENCES authors(slug) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_authors_author_slug_idx ON post_authors (author_slug);
+
+CREATE TABLE IF NOT EXISTS categories (
+	slug TEXT PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS post_categories (
+	post_id TEXT NOT NULL,
+	category_slug TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+
+	PRIMARY KEY (post_id, category_slug),
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+	FOREIGN KEY (category_slug) REFERENCES categories(slug) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_categories_category_slug_idx ON post_categories (category_slug);
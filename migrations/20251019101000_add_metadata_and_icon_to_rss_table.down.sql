@@ -0,0 +1,8 @@
+ALTER TABLE IF EXISTS rss
+DROP COLUMN language,
+DROP COLUMN generator,
+DROP COLUMN copyright,
+DROP COLUMN icon,
+DROP COLUMN icon_type,
+DROP COLUMN icon_url,
+DROP COLUMN icon_checked_at;
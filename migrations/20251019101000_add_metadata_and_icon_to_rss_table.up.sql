@@ -0,0 +1,8 @@
+ALTER TABLE IF EXISTS rss
+ADD COLUMN language TEXT NOT NULL DEFAULT '',
+ADD COLUMN generator TEXT NOT NULL DEFAULT '',
+ADD COLUMN copyright TEXT NOT NULL DEFAULT '',
+ADD COLUMN icon BYTEA,
+ADD COLUMN icon_type TEXT NOT NULL DEFAULT '',
+ADD COLUMN icon_url TEXT NOT NULL DEFAULT '',
+ADD COLUMN icon_checked_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_rss_id_pubdate_idx;
+DROP INDEX IF EXISTS subscriptions_rss_id_idx;
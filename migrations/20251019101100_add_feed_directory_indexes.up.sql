@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS subscriptions_rss_id_idx ON subscriptions (rss_id);
+CREATE INDEX IF NOT EXISTS posts_rss_id_pubdate_idx ON posts (rss_id, pubdate);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS post_states (
+	user_id TEXT NOT NULL,
+	post_id TEXT NOT NULL,
+	read_at TIMESTAMP,
+	starred_at TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (user_id, post_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_states_post_id_idx ON post_states (post_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS filter_rules (
+	id TEXT PRIMARY KEY NOT NULL,
+	user_id TEXT NOT NULL,
+	field TEXT NOT NULL,
+	match TEXT NOT NULL,
+	pattern TEXT NOT NULL,
+	action TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS filter_rules_user_id_idx ON filter_rules (user_id);
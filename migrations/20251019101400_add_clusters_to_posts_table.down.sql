@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS posts_pubdate_idx;
+DROP INDEX IF EXISTS posts_cluster_id_idx;
+DROP INDEX IF EXISTS posts_canonical_url_idx;
+
+-- links are unique again, only the oldest copy of a story is kept
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_rss_id_link_key;
+DELETE FROM posts p USING posts o
+WHERE p.link = o.link AND o.id < p.id;
+ALTER TABLE posts ADD CONSTRAINT posts_link_key UNIQUE (link);
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS canonical_url,
+DROP COLUMN IF EXISTS simhash,
+DROP COLUMN IF EXISTS cluster_id;
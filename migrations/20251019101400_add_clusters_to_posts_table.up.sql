@@ -0,0 +1,16 @@
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS canonical_url TEXT,
+ADD COLUMN IF NOT EXISTS simhash BIGINT,
+ADD COLUMN IF NOT EXISTS cluster_id TEXT;
+
+-- canonical urls of existing posts are filled in by `cli cron`, which
+-- normalizes them with urlnorm.Canonical
+UPDATE posts SET cluster_id = id WHERE cluster_id IS NULL;
+
+-- every feed keeps its own copy of a story so the copies can be clustered
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_link_key;
+ALTER TABLE posts ADD CONSTRAINT posts_rss_id_link_key UNIQUE (rss_id, link);
+
+CREATE INDEX IF NOT EXISTS posts_canonical_url_idx ON posts (canonical_url);
+CREATE INDEX IF NOT EXISTS posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX IF NOT EXISTS posts_pubdate_idx ON posts (pubdate);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS digest_settings (
+	user_id TEXT PRIMARY KEY NOT NULL,
+	frequency TEXT NOT NULL DEFAULT 'off',
+	hour INTEGER NOT NULL DEFAULT 8,
+	weekday INTEGER NOT NULL DEFAULT 1,
+	timezone TEXT NOT NULL DEFAULT 'UTC',
+	unsubscribe_token TEXT NOT NULL UNIQUE,
+	last_sent_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
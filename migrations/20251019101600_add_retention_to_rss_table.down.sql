@@ -0,0 +1,3 @@
+ALTER TABLE rss
+DROP COLUMN IF EXISTS retention_days,
+DROP COLUMN IF EXISTS retention_max_items;
@@ -0,0 +1,3 @@
+ALTER TABLE rss
+ADD COLUMN IF NOT EXISTS retention_days INTEGER,
+ADD COLUMN IF NOT EXISTS retention_max_items INTEGER;
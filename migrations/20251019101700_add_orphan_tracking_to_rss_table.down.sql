@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS rss_orphaned_since_idx;
+
+ALTER TABLE rss
+DROP COLUMN IF EXISTS last_fetched_at,
+DROP COLUMN IF EXISTS orphaned_since,
+DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,9 @@
+ALTER TABLE rss
+ADD COLUMN IF NOT EXISTS last_fetched_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS orphaned_since TIMESTAMP,
+ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+UPDATE rss SET orphaned_since = CURRENT_TIMESTAMP
+WHERE NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.rss_id = rss.id);
+
+CREATE INDEX IF NOT EXISTS rss_orphaned_since_idx ON rss (orphaned_since) WHERE orphaned_since IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS rss_feed_key_idx;
+ALTER TABLE rss DROP COLUMN IF EXISTS feed_key;
+
+-- several feeds may share a site link by now, so the unique constraint on
+-- link is not restored and rss_link_idx is kept in its place
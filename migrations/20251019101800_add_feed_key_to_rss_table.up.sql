@@ -0,0 +1,8 @@
+-- feeds are identified by their feed url, several feeds can share a site
+ALTER TABLE rss DROP CONSTRAINT IF EXISTS rss_link_key;
+CREATE INDEX IF NOT EXISTS rss_link_idx ON rss (link);
+
+-- keys of existing feeds are filled in by `cli feedkeys` and `cli cron`,
+-- which normalize them with urlnorm.FeedKey
+ALTER TABLE rss ADD COLUMN IF NOT EXISTS feed_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS rss_feed_key_idx ON rss (feed_key);